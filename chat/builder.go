@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"strconv"
+
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// Builder builds a component tree with chained calls, such as
+//
+//	chat.Text("Hello").Color(chat.Gold).Bold().OnClick(chat.RunCommand("/spawn"))
+//
+// A Builder is itself a Message, so it can be used wherever a component is
+// expected, including packet fields and as the child of another component.
+type Builder struct {
+	m Message
+}
+
+// Text will start building a text component.
+func Text(text string) *Builder {
+	return &Builder{m: &TextComponent{Text: text}}
+}
+
+// Keybind will start building a component showing the key bound to keybind.
+func Keybind(keybind string) *Builder {
+	return &Builder{m: &KeybindComponent{Keybind: keybind}}
+}
+
+// Selector will start building a component listing the entities matched by selector.
+func Selector(selector string) *Builder {
+	return &Builder{m: &SelectorComponent{Selector: selector}}
+}
+
+// ScoreOf will start building a component showing the score of name in objective.
+func ScoreOf(name, objective string) *Builder {
+	return &Builder{m: &ScoreComponent{Score: Score{Name: name, Objective: objective}}}
+}
+
+// Build will start building on an existing component, such as a translation.
+func Build(m Message) *Builder {
+	if b, ok := m.(*Builder); ok {
+		return b
+	}
+
+	return &Builder{m: m}
+}
+
+// Base will return the style and children of the component being built.
+func (b *Builder) Base() *Component {
+	return b.m.Base()
+}
+
+// Message will return the built component.
+func (b *Builder) Message() Message {
+	return b.m
+}
+
+// MarshalJSON will encode the built component as JSON.
+func (b *Builder) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(b.m, version.Latest)
+}
+
+// Color will set the color of the text.
+func (b *Builder) Color(c Color) *Builder {
+	b.Base().Color = c
+	return b
+}
+
+// Font will set the font of the text, such as "minecraft:uniform".
+func (b *Builder) Font(font string) *Builder {
+	b.Base().Font = font
+	return b
+}
+
+// Shadow will set the ARGB color of the text shadow.
+func (b *Builder) Shadow(argb int32) *Builder {
+	b.Base().ShadowColor = &argb
+	return b
+}
+
+// Bold will make the text bold.
+func (b *Builder) Bold() *Builder {
+	b.Base().Bold = boolPtr(true)
+	return b
+}
+
+// NotBold will stop the text from inheriting bold.
+func (b *Builder) NotBold() *Builder {
+	b.Base().Bold = boolPtr(false)
+	return b
+}
+
+// Italic will make the text italic.
+func (b *Builder) Italic() *Builder {
+	b.Base().Italic = boolPtr(true)
+	return b
+}
+
+// NotItalic will stop the text from inheriting italic, which item names and lore default to.
+func (b *Builder) NotItalic() *Builder {
+	b.Base().Italic = boolPtr(false)
+	return b
+}
+
+// Underlined will underline the text.
+func (b *Builder) Underlined() *Builder {
+	b.Base().Underlined = boolPtr(true)
+	return b
+}
+
+// NotUnderlined will stop the text from inheriting underlined.
+func (b *Builder) NotUnderlined() *Builder {
+	b.Base().Underlined = boolPtr(false)
+	return b
+}
+
+// Strikethrough will strike through the text.
+func (b *Builder) Strikethrough() *Builder {
+	b.Base().Strikethrough = boolPtr(true)
+	return b
+}
+
+// NotStrikethrough will stop the text from inheriting strikethrough.
+func (b *Builder) NotStrikethrough() *Builder {
+	b.Base().Strikethrough = boolPtr(false)
+	return b
+}
+
+// Obfuscated will make the text obfuscated.
+func (b *Builder) Obfuscated() *Builder {
+	b.Base().Obfuscated = boolPtr(true)
+	return b
+}
+
+// NotObfuscated will stop the text from inheriting obfuscated.
+func (b *Builder) NotObfuscated() *Builder {
+	b.Base().Obfuscated = boolPtr(false)
+	return b
+}
+
+// Insertion will set the text inserted into the chat box on shift-click.
+func (b *Builder) Insertion(text string) *Builder {
+	b.Base().Insertion = text
+	return b
+}
+
+// OnClick will set the action run when the text is clicked.
+func (b *Builder) OnClick(e *ClickEvent) *Builder {
+	b.Base().ClickEvent = e
+	return b
+}
+
+// OnHover will set the tooltip shown when hovering the text.
+func (b *Builder) OnHover(e *HoverEvent) *Builder {
+	b.Base().HoverEvent = e
+	return b
+}
+
+// Append will add children to the component, which inherit its style.
+func (b *Builder) Append(children ...Message) *Builder {
+	for _, child := range children {
+		b.Base().Append(unwrap(child))
+	}
+	return b
+}
+
+// AppendText will add a plain text child to the component.
+func (b *Builder) AppendText(text string) *Builder {
+	return b.Append(&TextComponent{Text: text})
+}
+
+// unwrap will return the component built by a Builder, or m itself.
+func unwrap(m Message) Message {
+	if b, ok := m.(*Builder); ok {
+		return b.m
+	}
+
+	return m
+}
+
+// OpenURL will create a click event opening url in the browser.
+func OpenURL(url string) *ClickEvent {
+	return &ClickEvent{Action: OpenUrlClickAction, Value: url}
+}
+
+// RunCommand will create a click event sending command as the player.
+func RunCommand(command string) *ClickEvent {
+	return &ClickEvent{Action: RunCommandClickAction, Value: command}
+}
+
+// SuggestCommand will create a click event putting command into the chat box.
+func SuggestCommand(command string) *ClickEvent {
+	return &ClickEvent{Action: SuggestCommandClickAction, Value: command}
+}
+
+// ChangePage will create a click event turning a book to page.
+func ChangePage(page int) *ClickEvent {
+	return &ClickEvent{Action: ChangePageClickAction, Value: strconv.Itoa(page)}
+}
+
+// CopyToClipboard will create a click event copying text to the clipboard.
+func CopyToClipboard(text string) *ClickEvent {
+	return &ClickEvent{Action: CopyToClipboardClickAction, Value: text}
+}
+
+// ShowText will create a hover event showing m as the tooltip.
+func ShowText(m Message) *HoverEvent {
+	return &HoverEvent{Action: ShowTextHoverAction, Text: unwrap(m)}
+}
+
+// ShowItemStack will create a hover event showing count of the item id.
+func ShowItemStack(id string, count int32) *HoverEvent {
+	return &HoverEvent{Action: ShowItem, Item: &HoverItem{ID: id, Count: count}}
+}
+
+// ShowEntityInfo will create a hover event showing the entity of the type and
+// UUID, with an optional name.
+func ShowEntityInfo(entityType, id string, name Message) *HoverEvent {
+	return &HoverEvent{Action: ShowEntity, Entity: &HoverEntity{Type: entityType, ID: id, Name: unwrap(name)}}
+}
@@ -1,5 +1,14 @@
 package chat
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidColor is returned for colors which are neither named nor in the "#RRGGBB" form.
+var ErrInvalidColor = errors.New("chat: invalid color")
+
 type Color string
 
 var Colors = []Color{
@@ -10,20 +19,124 @@ var Colors = []Color{
 }
 
 const (
-	White      	Color = "white"
-	Black       	  = "black"
-	DarkBlue          = "dark_blue"
-	DarkGreen         = "dark_green"
-	DarkAqua          = "dark_aqua"
-	DarkRed           = "dark_red"
-	DarkPurple        = "dark_purple"
-	Gold              = "gold"
-	Gray              = "gray"
-	DarkGray          = "dark_Gray"
-	Blue              = "blue"
-	Green             = "green"
-	Aqua              = "aqua"
-	Red               = "red"
-	LightPurple       = "light_purple"
-	Yellow            = "yellow"
+	White       Color = "white"
+	Black       Color = "black"
+	DarkBlue    Color = "dark_blue"
+	DarkGreen   Color = "dark_green"
+	DarkAqua    Color = "dark_aqua"
+	DarkRed     Color = "dark_red"
+	DarkPurple  Color = "dark_purple"
+	Gold        Color = "gold"
+	Gray        Color = "gray"
+	DarkGray    Color = "dark_gray"
+	Blue        Color = "blue"
+	Green       Color = "green"
+	Aqua        Color = "aqua"
+	Red         Color = "red"
+	LightPurple Color = "light_purple"
+	Yellow      Color = "yellow"
 )
+
+// colorValues are the RGB values of the named colors, in the order of Colors.
+var colorValues = [...][3]uint8{
+	{0x00, 0x00, 0x00}, {0x00, 0x00, 0xAA}, {0x00, 0xAA, 0x00}, {0x00, 0xAA, 0xAA},
+	{0xAA, 0x00, 0x00}, {0xAA, 0x00, 0xAA}, {0xFF, 0xAA, 0x00}, {0xAA, 0xAA, 0xAA},
+	{0x55, 0x55, 0x55}, {0x55, 0x55, 0xFF}, {0x55, 0xFF, 0x55}, {0x55, 0xFF, 0xFF},
+	{0xFF, 0x55, 0x55}, {0xFF, 0x55, 0xFF}, {0xFF, 0xFF, 0x55}, {0xFF, 0xFF, 0xFF},
+}
+
+// Hex will return the "#RRGGBB" color for the RGB values, supported since 1.16.
+func Hex(r, g, b uint8) Color {
+	return Color(fmt.Sprintf("#%02X%02X%02X", r, g, b))
+}
+
+// ParseColor will parse a named or "#RRGGBB" color.
+func ParseColor(s string) (Color, error) {
+	c := Color(strings.ToLower(s))
+	if c.IsHex() {
+		return Color(strings.ToUpper(s)), nil
+	}
+	if c.Named() {
+		return c, nil
+	}
+
+	return "", ErrInvalidColor
+}
+
+// Named reports whether the color is one of the 16 named colors.
+func (c Color) Named() bool {
+	return colorIndex(c) >= 0
+}
+
+// IsHex reports whether the color is in the "#RRGGBB" form.
+func (c Color) IsHex() bool {
+	_, _, _, ok := c.RGB()
+	return ok && !c.Named()
+}
+
+// Valid reports whether the color is named or in the "#RRGGBB" form.
+func (c Color) Valid() bool {
+	_, _, _, ok := c.RGB()
+	return ok
+}
+
+// Nearest will return the named color closest to the color, for clients
+// before 1.16. Invalid colors return an empty color.
+func (c Color) Nearest() Color {
+	if c.Named() {
+		return c
+	}
+
+	r, g, b, ok := c.RGB()
+	if !ok {
+		return ""
+	}
+
+	best, bestDist := Color(""), -1
+	for i, v := range colorValues {
+		dist := sq(int(v[0])-int(r)) + sq(int(v[1])-int(g)) + sq(int(v[2])-int(b))
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = Colors[i], dist
+		}
+	}
+
+	return best
+}
+
+// RGB will return the red, green and blue values of a named or "#RRGGBB" color.
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	if idx := colorIndex(c); idx >= 0 {
+		v := colorValues[idx]
+		return v[0], v[1], v[2], true
+	}
+
+	s := string(c)
+	if len(s) != 7 || s[0] != '#' {
+		return
+	}
+
+	var v [3]uint8
+	for i := range v {
+		hi, ok1 := hexDigit(s[1+2*i])
+		lo, ok2 := hexDigit(s[2+2*i])
+		if !ok1 || !ok2 {
+			return 0, 0, 0, false
+		}
+		v[i] = hi<<4 | lo
+	}
+
+	return v[0], v[1], v[2], true
+}
+
+func hexDigit(c byte) (uint8, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0', true
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10, true
+	case c >= 'A' && c <= 'F':
+		return c - 'A' + 10, true
+	}
+
+	return 0, false
+}
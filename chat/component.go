@@ -1,45 +1,246 @@
 package chat
 
+import (
+	"encoding/json"
+	"errors"
+
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// ErrUnknownComponent is returned when a value is not any known kind of component.
+var ErrUnknownComponent = errors.New("chat: unknown component kind")
+
+// Message is a chat component of any kind.
+type Message interface {
+	// Base will return the style and children shared by all kinds of components.
+	Base() *Component
+}
+
 type (
 	TextComponent struct {
-		Text string `json:"text"`
+		Text string
 
 		Component
 	}
 
 	TranslateComponent struct {
-		Translate string   `json:"translate"`
-		With      []string `json:"with"`
+		Translate string
+		With      Messages
+		Fallback  string
 
 		Component
 	}
 
 	ScoreComponent struct {
-		Name      string `json:"name"`
-		Objective string `json:"objective"`
+		Score Score
 
 		Component
 	}
 
 	SelectorComponent struct {
-		Selector string   `json:"selector"`
-		Args     []string `json:"extra"`
+		Selector  string
+		Separator Message
+
+		Component
+	}
+
+	KeybindComponent struct {
+		Keybind string
+
+		Component
+	}
+
+	NBTComponent struct {
+		NBT       string
+		Interpret *bool
+		Separator Message
+
+		// Exactly one of Block, Entity or Storage names the source of the data.
+		Block   string
+		Entity  string
+		Storage string
 
 		Component
 	}
 
+	Score struct {
+		Name      string
+		Objective string
+		Value     string
+	}
+
+	// Component holds the fields shared by all kinds of components. Unset
+	// style fields are left out when encoding and inherited from the parent.
 	Component struct {
-		Bold          bool `json:"bold"`
-		Italic        bool `json:"italic"`
-		Underlined    bool `json:"underlined"`
-		Strikethrough bool `json:"strikethrough"`
-		Obfuscated    bool `json:"obfuscated"`
+		Bold          *bool
+		Italic        *bool
+		Underlined    *bool
+		Strikethrough *bool
+		Obfuscated    *bool
+
+		Color Color
+		Font  string
+
+		// ShadowColor is the ARGB color of the text shadow, supported since 1.21.4.
+		ShadowColor *int32
 
-		Color Color `json:"color"`
+		ClickEvent *ClickEvent
+		HoverEvent *HoverEvent
 
-		ClickEvent ClickEvent `json:"clickEvent"`
-		HoverEvent HoverEvent `json:"hoverEvent"`
+		Insertion string
 
-		Insertion string `json:"insertion"`
+		Extra Messages
 	}
+
+	// Messages is a list of components of any kind.
+	Messages []Message
 )
+
+// Base will return the component itself.
+func (c *Component) Base() *Component {
+	return c
+}
+
+// Append will add the messages as children of the component.
+func (c *Component) Append(m ...Message) {
+	c.Extra = append(c.Extra, m...)
+}
+
+// The JSON methods let components be used in structs encoded with
+// encoding/json, they use the format of version.Latest.
+
+// MarshalJSON will encode the component as JSON.
+func (c TextComponent) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(&c, version.Latest)
+}
+
+// MarshalJSON will encode the component as JSON.
+func (c TranslateComponent) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(&c, version.Latest)
+}
+
+// MarshalJSON will encode the component as JSON.
+func (c ScoreComponent) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(&c, version.Latest)
+}
+
+// MarshalJSON will encode the component as JSON.
+func (c SelectorComponent) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(&c, version.Latest)
+}
+
+// MarshalJSON will encode the component as JSON.
+func (c KeybindComponent) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(&c, version.Latest)
+}
+
+// MarshalJSON will encode the component as JSON.
+func (c NBTComponent) MarshalJSON() ([]byte, error) {
+	return MarshalVersion(&c, version.Latest)
+}
+
+// UnmarshalJSON will decode a text component. Any other kind of component is
+// added as the only child of an empty text component.
+func (c *TextComponent) UnmarshalJSON(data []byte) error {
+	m, err := Unmarshal(data)
+	if err != nil {
+		return err
+	}
+
+	if t, ok := m.(*TextComponent); ok {
+		*c = *t
+	} else {
+		*c = TextComponent{}
+		c.Append(m)
+	}
+
+	return nil
+}
+
+// UnmarshalJSON will decode a translate component.
+func (c *TranslateComponent) UnmarshalJSON(data []byte) error {
+	m, err := unmarshalKind(data, c)
+	if err == nil {
+		*c = *m.(*TranslateComponent)
+	}
+	return err
+}
+
+// UnmarshalJSON will decode a score component.
+func (c *ScoreComponent) UnmarshalJSON(data []byte) error {
+	m, err := unmarshalKind(data, c)
+	if err == nil {
+		*c = *m.(*ScoreComponent)
+	}
+	return err
+}
+
+// UnmarshalJSON will decode a selector component.
+func (c *SelectorComponent) UnmarshalJSON(data []byte) error {
+	m, err := unmarshalKind(data, c)
+	if err == nil {
+		*c = *m.(*SelectorComponent)
+	}
+	return err
+}
+
+// UnmarshalJSON will decode a keybind component.
+func (c *KeybindComponent) UnmarshalJSON(data []byte) error {
+	m, err := unmarshalKind(data, c)
+	if err == nil {
+		*c = *m.(*KeybindComponent)
+	}
+	return err
+}
+
+// UnmarshalJSON will decode an NBT component.
+func (c *NBTComponent) UnmarshalJSON(data []byte) error {
+	m, err := unmarshalKind(data, c)
+	if err == nil {
+		*c = *m.(*NBTComponent)
+	}
+	return err
+}
+
+// unmarshalKind will decode a component which has to be of the same kind as want.
+func unmarshalKind(data []byte, want Message) (Message, error) {
+	m, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	if componentKind(m) != componentKind(want) {
+		return nil, ErrUnknownComponent
+	}
+
+	return m, nil
+}
+
+// MarshalJSON will encode the list of components as JSON.
+func (m Messages) MarshalJSON() ([]byte, error) {
+	list := make([]interface{}, 0, len(m))
+	for _, msg := range m {
+		list = append(list, toValue(msg, version.Latest))
+	}
+
+	return encodeJSON(list)
+}
+
+// UnmarshalJSON will decode a list of components of any kind.
+func (m *Messages) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	list := make(Messages, 0, len(raw))
+	for _, r := range raw {
+		msg, err := Unmarshal(r)
+		if err != nil {
+			return err
+		}
+		list = append(list, msg)
+	}
+
+	*m = list
+	return nil
+}
@@ -4,24 +4,53 @@ type (
 	ClickAction string
 	HoverAction string
 
+	// ClickEvent is the action run when the text is clicked. Value holds the
+	// URL, command, page number or text to copy, depending on the action.
 	ClickEvent struct {
 		Action ClickAction
 		Value  string
 	}
 
+	// HoverEvent is the tooltip shown when hovering the text. Only the field
+	// matching the action is used.
 	HoverEvent struct {
 		Action HoverAction
-		Value  interface{}
+
+		Text   Message
+		Item   *HoverItem
+		Entity *HoverEntity
+	}
+
+	// HoverItem is the item shown by a ShowItem hover event.
+	HoverItem struct {
+		ID    string
+		Count int32
+
+		// Tag is the item tag as SNBT, used before 1.20.5.
+		Tag string
+
+		// Components are the item components, used since 1.20.5.
+		Components map[string]interface{}
+	}
+
+	// HoverEntity is the entity shown by a ShowEntity hover event.
+	HoverEntity struct {
+		Type string
+		ID   string
+		Name Message
 	}
 )
 
 const (
-	OpenUrlClickAction  ClickAction = "open_url"
-	OpenFileClickAction 			= "open_file"
-	RunCommandClickAction           = "run_command"
-	SuggestCommandClickAction       = "suggest_command"
+	OpenUrlClickAction         ClickAction = "open_url"
+	OpenFileClickAction        ClickAction = "open_file"
+	RunCommandClickAction      ClickAction = "run_command"
+	SuggestCommandClickAction  ClickAction = "suggest_command"
+	ChangePageClickAction      ClickAction = "change_page"
+	CopyToClipboardClickAction ClickAction = "copy_to_clipboard"
 
 	ShowTextHoverAction HoverAction = "show_text"
-	ShowAchievement                 = "show_achievement"
-	ShowItem                        = "show_item"
+	ShowAchievement     HoverAction = "show_achievement"
+	ShowItem            HoverAction = "show_item"
+	ShowEntity          HoverAction = "show_entity"
 )
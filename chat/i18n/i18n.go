@@ -0,0 +1,164 @@
+// Package i18n loads Minecraft language files, such as the en_us.json found in
+// the client assets, and resolves chat translations with them.
+package i18n
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"justanother.org/protocolhelper/chat"
+)
+
+// DefaultLocale is the locale used when a key is missing from the requested one.
+const DefaultLocale = "en_us"
+
+// Language maps translation keys to their format strings.
+type Language map[string]string
+
+// LoadFile will read a JSON language file.
+func LoadFile(path string) (Language, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var lang Language
+	if err = json.Unmarshal(data, &lang); err != nil {
+		return nil, err
+	}
+
+	return lang, nil
+}
+
+// Translator holds the languages of a set of locales. Keys missing from a
+// locale are looked up in the fallback locale.
+type Translator struct {
+	Fallback string
+
+	mu        sync.RWMutex
+	languages map[string]Language
+	merged    map[string]Language
+}
+
+// NewTranslator will create a translator falling back to DefaultLocale.
+func NewTranslator() *Translator {
+	return &Translator{
+		Fallback:  DefaultLocale,
+		languages: make(map[string]Language),
+		merged:    make(map[string]Language),
+	}
+}
+
+// NormalizeLocale will turn a locale such as "en-US" into the "en_us" form used by the language files.
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "-", "_"))
+}
+
+// Add will add the keys of lang to the locale, replacing existing keys.
+func (t *Translator) Add(locale string, lang Language) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	locale = NormalizeLocale(locale)
+	if t.languages[locale] == nil {
+		t.languages[locale] = make(Language, len(lang))
+	}
+	for k, v := range lang {
+		t.languages[locale][k] = v
+	}
+
+	// Any merged locale may fall back to this one.
+	t.merged = make(map[string]Language)
+}
+
+// LoadFile will add the keys of the language file at path to the locale.
+func (t *Translator) LoadFile(locale, path string) error {
+	lang, err := LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	t.Add(locale, lang)
+	return nil
+}
+
+// LoadDir will load every *.json file in dir, named after its locale.
+func (t *Translator) LoadDir(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		locale := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if err = t.LoadFile(locale, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Locales will return the locales known to the translator.
+func (t *Translator) Locales() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	locales := make([]string, 0, len(t.languages))
+	for locale := range t.languages {
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
+
+// Language will return the keys of the locale merged over those of the
+// fallback locale. The result is shared and must not be modified.
+func (t *Translator) Language(locale string) Language {
+	locale = NormalizeLocale(locale)
+
+	t.mu.RLock()
+	lang, ok := t.merged[locale]
+	t.mu.RUnlock()
+	if ok {
+		return lang
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lang = make(Language)
+	for k, v := range t.languages[NormalizeLocale(t.Fallback)] {
+		lang[k] = v
+	}
+	for k, v := range t.languages[locale] {
+		lang[k] = v
+	}
+
+	t.merged[locale] = lang
+	return lang
+}
+
+// Lookup will return the format string of key in the locale or the fallback locale.
+func (t *Translator) Lookup(locale, key string) (string, bool) {
+	format, ok := t.Language(locale)[key]
+	return format, ok
+}
+
+// Resolve will replace the translations in the message with their text in the locale.
+func (t *Translator) Resolve(m chat.Message, locale string) chat.Message {
+	return chat.Resolve(m, t.Language(locale))
+}
+
+// PlainText will render the message as text in the locale.
+func (t *Translator) PlainText(m chat.Message, locale string) string {
+	return chat.PlainText(m, t.Language(locale))
+}
+
+// ANSI will render the message for terminals in the locale.
+func (t *Translator) ANSI(m chat.Message, locale string, mode chat.ColorMode) string {
+	return chat.ANSI(m, t.Language(locale), mode)
+}
@@ -0,0 +1,705 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// Components are encoded through a tree of plain values (maps, lists,
+// strings, numbers and booleans) which is then written as JSON or NBT. The
+// shape of that tree depends on the protocol version: hover events moved from
+// a legacy "value" to "contents" in 1.16, and 1.21.5 renamed the event fields
+// to snake_case and flattened their payloads. Decoding accepts every format.
+
+// Marshal will encode the message as JSON in the format of version.Latest.
+func Marshal(m Message) ([]byte, error) {
+	return MarshalVersion(m, version.Latest)
+}
+
+// MarshalVersion will encode the message as JSON in the format understood by
+// clients of the protocol version.
+func MarshalVersion(m Message, protocol uint16) ([]byte, error) {
+	return encodeJSON(toValue(m, protocol))
+}
+
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Unmarshal will decode a chat JSON value of any shape: a plain string, an
+// array whose first element is the parent of the others, or an object of any
+// known component kind, in the format of any protocol version.
+func Unmarshal(data []byte) (Message, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return fromValue(v)
+}
+
+func componentKind(m Message) string {
+	switch unwrap(m).(type) {
+	case *TextComponent:
+		return "text"
+	case *TranslateComponent:
+		return "translatable"
+	case *ScoreComponent:
+		return "score"
+	case *SelectorComponent:
+		return "selector"
+	case *KeybindComponent:
+		return "keybind"
+	case *NBTComponent:
+		return "nbt"
+	}
+
+	return ""
+}
+
+func toValue(m Message, protocol uint16) interface{} {
+	if m == nil {
+		return ""
+	}
+	m = unwrap(m)
+
+	v := make(map[string]interface{})
+	switch c := m.(type) {
+	case *TextComponent:
+		v["text"] = c.Text
+	case *TranslateComponent:
+		v["translate"] = c.Translate
+		if len(c.With) > 0 {
+			v["with"] = listValue(c.With, protocol)
+		}
+		if c.Fallback != "" {
+			v["fallback"] = c.Fallback
+		}
+	case *ScoreComponent:
+		score := map[string]interface{}{"name": c.Score.Name, "objective": c.Score.Objective}
+		if c.Score.Value != "" {
+			score["value"] = c.Score.Value
+		}
+		v["score"] = score
+	case *SelectorComponent:
+		v["selector"] = c.Selector
+		if c.Separator != nil {
+			v["separator"] = toValue(c.Separator, protocol)
+		}
+	case *KeybindComponent:
+		v["keybind"] = c.Keybind
+	case *NBTComponent:
+		v["nbt"] = c.NBT
+		if c.Interpret != nil {
+			v["interpret"] = *c.Interpret
+		}
+		if c.Separator != nil {
+			v["separator"] = toValue(c.Separator, protocol)
+		}
+		for k, s := range map[string]string{"block": c.Block, "entity": c.Entity, "storage": c.Storage} {
+			if s != "" {
+				v[k] = s
+			}
+		}
+	}
+
+	base := m.Base()
+	for k, b := range map[string]*bool{
+		"bold":          base.Bold,
+		"italic":        base.Italic,
+		"underlined":    base.Underlined,
+		"strikethrough": base.Strikethrough,
+		"obfuscated":    base.Obfuscated,
+	} {
+		if b != nil {
+			v[k] = *b
+		}
+	}
+	for k, s := range map[string]string{"color": string(base.Color), "font": base.Font, "insertion": base.Insertion} {
+		if s != "" {
+			v[k] = s
+		}
+	}
+	if base.ShadowColor != nil {
+		v["shadow_color"] = *base.ShadowColor
+	}
+
+	if base.ClickEvent != nil {
+		if protocol >= version.V1_21_5 {
+			v["click_event"] = clickValue(base.ClickEvent)
+		} else {
+			v["clickEvent"] = map[string]interface{}{"action": string(base.ClickEvent.Action), "value": base.ClickEvent.Value}
+		}
+	}
+	if base.HoverEvent != nil {
+		if protocol >= version.V1_21_5 {
+			v["hover_event"] = hoverValue(base.HoverEvent, protocol)
+		} else {
+			v["hoverEvent"] = hoverValue(base.HoverEvent, protocol)
+		}
+	}
+
+	if len(base.Extra) > 0 {
+		v["extra"] = listValue(base.Extra, protocol)
+	}
+
+	return v
+}
+
+func listValue(list Messages, protocol uint16) []interface{} {
+	out := make([]interface{}, 0, len(list))
+	for _, m := range list {
+		out = append(out, toValue(m, protocol))
+	}
+
+	return out
+}
+
+// clickValue will return the 1.21.5 form of a click event.
+func clickValue(e *ClickEvent) map[string]interface{} {
+	v := map[string]interface{}{"action": string(e.Action)}
+	switch e.Action {
+	case OpenUrlClickAction:
+		v["url"] = e.Value
+	case OpenFileClickAction:
+		v["path"] = e.Value
+	case RunCommandClickAction, SuggestCommandClickAction:
+		v["command"] = e.Value
+	case ChangePageClickAction:
+		page, _ := strconv.Atoi(e.Value)
+		v["page"] = int32(page)
+	default:
+		v["value"] = e.Value
+	}
+
+	return v
+}
+
+func hoverValue(e *HoverEvent, protocol uint16) map[string]interface{} {
+	v := map[string]interface{}{"action": string(e.Action)}
+
+	var contents interface{}
+	switch {
+	case e.Action == ShowTextHoverAction:
+		contents = toValue(e.Text, protocol)
+	case e.Action == ShowItem && e.Item != nil:
+		if protocol < version.V1_16 {
+			contents = itemSNBT(e.Item)
+			break
+		}
+
+		item := map[string]interface{}{"id": e.Item.ID}
+		if e.Item.Count > 1 {
+			item["count"] = e.Item.Count
+		}
+		if protocol >= version.V1_20_5 && len(e.Item.Components) > 0 {
+			item["components"] = e.Item.Components
+		} else if protocol < version.V1_20_5 && e.Item.Tag != "" {
+			item["tag"] = e.Item.Tag
+		}
+		contents = item
+	case e.Action == ShowEntity && e.Entity != nil:
+		if protocol < version.V1_16 {
+			contents = entitySNBT(e.Entity, protocol)
+			break
+		}
+
+		entity := map[string]interface{}{"type": e.Entity.Type, "id": e.Entity.ID}
+		if protocol >= version.V1_21_5 {
+			entity = map[string]interface{}{"id": e.Entity.Type, "uuid": e.Entity.ID}
+		}
+		if e.Entity.Name != nil {
+			entity["name"] = toValue(e.Entity.Name, protocol)
+		}
+		contents = entity
+	case e.Text != nil:
+		contents = ownText(e.Text)
+	default:
+		return v
+	}
+
+	switch {
+	case protocol >= version.V1_21_5 && e.Action != ShowTextHoverAction:
+		if inline, ok := contents.(map[string]interface{}); ok {
+			for k, c := range inline {
+				v[k] = c
+			}
+			break
+		}
+		v["value"] = contents
+	case protocol >= version.V1_21_5 || protocol < version.V1_16:
+		v["value"] = contents
+	default:
+		v["contents"] = contents
+	}
+
+	return v
+}
+
+// itemSNBT will write the item in the SNBT form used by hover events before 1.16.
+func itemSNBT(item *HoverItem) string {
+	count := item.Count
+	if count == 0 {
+		count = 1
+	}
+
+	s := fmt.Sprintf("{id:%s,Count:%db", strconv.Quote(item.ID), count)
+	if item.Tag != "" {
+		s += ",tag:" + item.Tag
+	}
+
+	return s + "}"
+}
+
+// entitySNBT will write the entity in the SNBT form used by hover events before 1.16.
+func entitySNBT(entity *HoverEntity, protocol uint16) string {
+	s := fmt.Sprintf("{type:%s,id:%s", strconv.Quote(entity.Type), strconv.Quote(entity.ID))
+	if entity.Name != nil {
+		name, _ := MarshalVersion(entity.Name, protocol)
+		s += ",name:" + strconv.Quote(string(name))
+	}
+
+	return s + "}"
+}
+
+func fromValue(v interface{}) (Message, error) {
+	switch val := v.(type) {
+	case string:
+		return &TextComponent{Text: val}, nil
+	case []interface{}:
+		list, err := listFromValue(val)
+		if err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, ErrUnknownComponent
+		}
+
+		list[0].Base().Append(list[1:]...)
+		return list[0], nil
+	case map[string]interface{}:
+		return objectFromValue(val)
+	case nil:
+		return nil, ErrUnknownComponent
+	}
+
+	// Numbers and booleans are turned into text, like the vanilla client does.
+	return &TextComponent{Text: fmt.Sprint(v)}, nil
+}
+
+func listFromValue(list []interface{}) (Messages, error) {
+	out := make(Messages, 0, len(list))
+	for _, item := range list {
+		m, err := fromValue(item)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+
+	return out, nil
+}
+
+func objectFromValue(v map[string]interface{}) (Message, error) {
+	var (
+		m   Message
+		err error
+	)
+
+	switch {
+	case v["text"] != nil:
+		m = &TextComponent{Text: asString(v["text"])}
+	case v["translate"] != nil:
+		t := &TranslateComponent{Translate: asString(v["translate"]), Fallback: asString(v["fallback"])}
+		if with, ok := v["with"].([]interface{}); ok {
+			if t.With, err = listFromValue(with); err != nil {
+				return nil, err
+			}
+		}
+		m = t
+	case v["score"] != nil:
+		score, _ := v["score"].(map[string]interface{})
+		m = &ScoreComponent{Score: Score{
+			Name:      asString(score["name"]),
+			Objective: asString(score["objective"]),
+			Value:     asString(score["value"]),
+		}}
+	case v["selector"] != nil:
+		s := &SelectorComponent{Selector: asString(v["selector"])}
+		if s.Separator, err = optionalFromValue(v["separator"]); err != nil {
+			return nil, err
+		}
+		m = s
+	case v["keybind"] != nil:
+		m = &KeybindComponent{Keybind: asString(v["keybind"])}
+	case v["nbt"] != nil:
+		n := &NBTComponent{
+			NBT:     asString(v["nbt"]),
+			Block:   asString(v["block"]),
+			Entity:  asString(v["entity"]),
+			Storage: asString(v["storage"]),
+		}
+		if b, ok := asBool(v["interpret"]); ok {
+			n.Interpret = &b
+		}
+		if n.Separator, err = optionalFromValue(v["separator"]); err != nil {
+			return nil, err
+		}
+		m = n
+	case v[""] != nil && len(v) == 1:
+		// Lists in NBT have to hold a single type, so other values are wrapped in a compound with an empty key.
+		return fromValue(v[""])
+	default:
+		return nil, ErrUnknownComponent
+	}
+
+	base := m.Base()
+	for k, dst := range map[string]**bool{
+		"bold":          &base.Bold,
+		"italic":        &base.Italic,
+		"underlined":    &base.Underlined,
+		"strikethrough": &base.Strikethrough,
+		"obfuscated":    &base.Obfuscated,
+	} {
+		if b, ok := asBool(v[k]); ok {
+			*dst = &b
+		}
+	}
+
+	base.Color = Color(asString(v["color"]))
+	base.Font = asString(v["font"])
+	base.Insertion = asString(v["insertion"])
+	if color, ok := shadowColor(v["shadow_color"]); ok {
+		base.ShadowColor = &color
+	}
+
+	if e, ok := v["click_event"].(map[string]interface{}); ok {
+		base.ClickEvent = clickFromValue(e)
+	} else if e, ok := v["clickEvent"].(map[string]interface{}); ok {
+		base.ClickEvent = clickFromValue(e)
+	}
+
+	if e, ok := v["hover_event"].(map[string]interface{}); ok {
+		base.HoverEvent, err = hoverFromValue(e)
+	} else if e, ok := v["hoverEvent"].(map[string]interface{}); ok {
+		base.HoverEvent, err = hoverFromValue(e)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if extra, ok := v["extra"].([]interface{}); ok {
+		if base.Extra, err = listFromValue(extra); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func optionalFromValue(v interface{}) (Message, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	return fromValue(v)
+}
+
+func clickFromValue(v map[string]interface{}) *ClickEvent {
+	e := &ClickEvent{Action: ClickAction(asString(v["action"]))}
+	for _, k := range []string{"value", "url", "path", "command", "page"} {
+		if v[k] != nil {
+			e.Value = asString(v[k])
+			break
+		}
+	}
+
+	return e
+}
+
+func hoverFromValue(v map[string]interface{}) (*HoverEvent, error) {
+	e := &HoverEvent{Action: HoverAction(asString(v["action"]))}
+
+	contents, legacy := v["contents"], false
+	if contents == nil {
+		contents = v["value"]
+		legacy = e.Action != ShowTextHoverAction
+	}
+
+	var err error
+	switch e.Action {
+	case ShowTextHoverAction:
+		e.Text, err = optionalFromValue(contents)
+	case ShowItem:
+		item := &HoverItem{Count: 1}
+		var fields map[string]interface{}
+		switch {
+		case v["id"] != nil:
+			fields = v
+		case legacy && contents != nil:
+			fields, err = snbtFromValue(contents)
+		case contents != nil:
+			if id, ok := contents.(string); ok {
+				fields = map[string]interface{}{"id": id}
+			} else {
+				fields, _ = contents.(map[string]interface{})
+			}
+		}
+
+		item.ID = asString(fields["id"])
+		for _, k := range []string{"count", "Count"} {
+			if n, ok := asInt(fields[k]); ok {
+				item.Count = int32(n)
+			}
+		}
+		item.Tag = asString(fields["tag"])
+		item.Components, _ = fields["components"].(map[string]interface{})
+		e.Item = item
+	case ShowEntity:
+		entity := &HoverEntity{}
+		var fields map[string]interface{}
+		switch {
+		case v["uuid"] != nil:
+			entity.Type, entity.ID = asString(v["id"]), uuidString(v["uuid"])
+			fields = v
+		case legacy && contents != nil:
+			fields, err = snbtFromValue(contents)
+			entity.Type, entity.ID = asString(fields["type"]), asString(fields["id"])
+			if name, ok := fields["name"].(string); ok {
+				entity.Name, err = Unmarshal([]byte(name))
+			}
+			e.Entity = entity
+			return e, err
+		default:
+			fields, _ = contents.(map[string]interface{})
+			entity.Type, entity.ID = asString(fields["type"]), uuidString(fields["id"])
+		}
+
+		if entity.Name, err = optionalFromValue(fields["name"]); err != nil {
+			return nil, err
+		}
+		e.Entity = entity
+	default:
+		if contents != nil {
+			e.Text, err = fromValue(contents)
+		}
+	}
+
+	return e, err
+}
+
+// snbtFromValue will read the SNBT compound held by the legacy value of a hover event.
+func snbtFromValue(v interface{}) (map[string]interface{}, error) {
+	m, err := fromValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSNBTCompound(PlainText(m, nil)), nil
+}
+
+// parseSNBTCompound will split the top level of an SNBT compound into its
+// keys and values. Strings are unquoted, numbers lose their type suffix and
+// nested compounds and lists are kept as SNBT.
+func parseSNBTCompound(s string) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+		return out
+	}
+	s = s[1 : len(s)-1]
+
+	for len(s) > 0 {
+		colon := strings.IndexByte(s, ':')
+		if colon < 0 {
+			break
+		}
+		key := strings.Trim(strings.TrimSpace(s[:colon]), `"'`)
+		s = strings.TrimSpace(s[colon+1:])
+
+		end, depth, quote := 0, 0, byte(0)
+	scan:
+		for ; end < len(s); end++ {
+			c := s[end]
+			switch {
+			case quote != 0:
+				if c == '\\' {
+					end++
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '{' || c == '[':
+				depth++
+			case c == '}' || c == ']':
+				depth--
+			case c == ',' && depth == 0:
+				break scan
+			}
+		}
+
+		raw := strings.TrimSpace(s[:end])
+		switch {
+		case len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\''):
+			if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(raw[1:len(raw)-1], `\'`, `'`) + `"`); err == nil {
+				out[key] = unquoted
+			} else {
+				out[key] = raw[1 : len(raw)-1]
+			}
+		case raw != "" && strings.ContainsRune("bBsSlLfFdD", rune(raw[len(raw)-1])):
+			if n, err := strconv.ParseFloat(raw[:len(raw)-1], 64); err == nil {
+				out[key] = n
+			} else {
+				out[key] = raw
+			}
+		default:
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				out[key] = n
+			} else {
+				out[key] = raw
+			}
+		}
+
+		if end >= len(s) {
+			break
+		}
+		s = s[end+1:]
+	}
+
+	return out
+}
+
+func asString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	}
+
+	return fmt.Sprint(v)
+}
+
+func asInt(v interface{}) (int64, bool) {
+	switch val := v.(type) {
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n, true
+		}
+		f, err := val.Float64()
+		return int64(f), err == nil
+	case float64:
+		return int64(val), true
+	case float32:
+		return int64(val), true
+	case int:
+		return int64(val), true
+	case int8:
+		return int64(val), true
+	case int16:
+		return int64(val), true
+	case int32:
+		return int64(val), true
+	case int64:
+		return val, true
+	}
+
+	return 0, false
+}
+
+// asBool will read a boolean, which NBT stores as a byte.
+func asBool(v interface{}) (bool, bool) {
+	if b, ok := v.(bool); ok {
+		return b, true
+	}
+	if s, ok := v.(string); ok {
+		b, err := strconv.ParseBool(s)
+		return b, err == nil
+	}
+
+	n, ok := asInt(v)
+	return n != 0, ok
+}
+
+// shadowColor will read a shadow color given as an ARGB integer or a list of four floats.
+func shadowColor(v interface{}) (int32, bool) {
+	if n, ok := asInt(v); ok {
+		return int32(n), true
+	}
+
+	var floats []float64
+	switch list := v.(type) {
+	case []interface{}:
+		for _, item := range list {
+			switch f := item.(type) {
+			case json.Number:
+				n, _ := f.Float64()
+				floats = append(floats, n)
+			case float32:
+				floats = append(floats, float64(f))
+			case float64:
+				floats = append(floats, f)
+			}
+		}
+	case []float32:
+		for _, f := range list {
+			floats = append(floats, float64(f))
+		}
+	}
+	if len(floats) != 4 {
+		return 0, false
+	}
+
+	var argb uint32
+	for i, f := range []float64{floats[3], floats[0], floats[1], floats[2]} {
+		argb |= uint32(f*255+0.5) & 0xFF << uint(24-8*i)
+	}
+
+	return int32(argb), true
+}
+
+// uuidString will read a UUID given as a string or as an array of four ints.
+func uuidString(v interface{}) string {
+	var ints []int64
+	switch val := v.(type) {
+	case string:
+		return val
+	case []int32:
+		for _, i := range val {
+			ints = append(ints, int64(i))
+		}
+	case []interface{}:
+		for _, i := range val {
+			n, _ := asInt(i)
+			ints = append(ints, n)
+		}
+	}
+	if len(ints) != 4 {
+		return asString(v)
+	}
+
+	var b [16]byte
+	for i, n := range ints {
+		u := uint32(n)
+		b[4*i], b[4*i+1], b[4*i+2], b[4*i+3] = byte(u>>24), byte(u>>16), byte(u>>8), byte(u)
+	}
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
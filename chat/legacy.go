@@ -0,0 +1,279 @@
+package chat
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Characters introducing a legacy formatting code.
+const (
+	SectionSign   = '§'
+	AlternateChar = '&'
+)
+
+// legacyColors are the legacy color codes, in the order of Colors.
+const legacyColors = "0123456789abcdef"
+
+// legacyStyle is the formatting state while reading or writing legacy text.
+type legacyStyle struct {
+	color                                               Color
+	bold, italic, underlined, strikethrough, obfuscated bool
+}
+
+func (s legacyStyle) component(text string) *TextComponent {
+	t := &TextComponent{Text: text}
+	t.Color = s.color
+	for _, f := range []struct {
+		set bool
+		dst **bool
+	}{
+		{s.bold, &t.Bold},
+		{s.italic, &t.Italic},
+		{s.underlined, &t.Underlined},
+		{s.strikethrough, &t.Strikethrough},
+		{s.obfuscated, &t.Obfuscated},
+	} {
+		if f.set {
+			*f.dst = boolPtr(true)
+		}
+	}
+
+	return t
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+// ParseLegacy will turn text using legacy formatting codes introduced by char,
+// usually SectionSign or AlternateChar, into a component tree. Hex colors are
+// read from the "§x§r§r§g§g§b§b" form. Unknown codes are kept as text.
+func ParseLegacy(s string, char rune) Message {
+	root := &TextComponent{}
+
+	var (
+		style legacyStyle
+		text  strings.Builder
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			root.Append(style.component(text.String()))
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r != char || i+size >= len(s) {
+			text.WriteRune(r)
+			i += size
+			continue
+		}
+
+		code, codeSize := utf8.DecodeRuneInString(s[i+size:])
+		code = toLower(code)
+
+		if code == 'x' {
+			if hex, n := parseLegacyHex(s[i+size+codeSize:], char); n > 0 {
+				flush()
+				style = legacyStyle{color: Color("#" + hex)}
+				i += size + codeSize + n
+				continue
+			}
+		}
+
+		next := style
+		switch {
+		case strings.ContainsRune(legacyColors, code):
+			next = legacyStyle{color: Colors[strings.IndexRune(legacyColors, code)]}
+		case code == 'r':
+			next = legacyStyle{}
+		case code == 'k':
+			next.obfuscated = true
+		case code == 'l':
+			next.bold = true
+		case code == 'm':
+			next.strikethrough = true
+		case code == 'n':
+			next.underlined = true
+		case code == 'o':
+			next.italic = true
+		default:
+			text.WriteRune(r)
+			i += size
+			continue
+		}
+
+		flush()
+		style = next
+		i += size + codeSize
+	}
+	flush()
+
+	if len(root.Extra) == 1 {
+		if t, ok := root.Extra[0].(*TextComponent); ok {
+			return t
+		}
+	}
+
+	return root
+}
+
+// parseLegacyHex will read the six "§r" pairs following "§x", returning the
+// hex digits and the number of bytes consumed, or zero if they are not valid.
+func parseLegacyHex(s string, char rune) (string, int) {
+	var (
+		hex strings.Builder
+		n   int
+	)
+
+	for i := 0; i < 6; i++ {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if r != char {
+			return "", 0
+		}
+		n += size
+
+		d, size := utf8.DecodeRuneInString(s[n:])
+		d = toLower(d)
+		if !strings.ContainsRune("0123456789abcdef", d) {
+			return "", 0
+		}
+		n += size
+
+		hex.WriteRune(d)
+	}
+
+	return hex.String(), n
+}
+
+func toLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
+
+// ToLegacy will flatten the component tree into text using legacy formatting
+// codes introduced by char, for clients and consoles without JSON chat. Hex
+// colors are written as the nearest named color.
+func ToLegacy(m Message, char rune) string {
+	var (
+		buf     strings.Builder
+		current legacyStyle
+	)
+
+	walk(m, legacyStyle{}, nil, func(text string, style legacyStyle) {
+		if text == "" {
+			return
+		}
+
+		if style != current {
+			if idx := colorIndex(style.color.Nearest()); idx >= 0 {
+				buf.WriteRune(char)
+				buf.WriteByte(legacyColors[idx])
+			} else if current != (legacyStyle{}) {
+				buf.WriteRune(char)
+				buf.WriteByte('r')
+			}
+
+			for _, f := range []struct {
+				set  bool
+				code byte
+			}{
+				{style.obfuscated, 'k'},
+				{style.bold, 'l'},
+				{style.strikethrough, 'm'},
+				{style.underlined, 'n'},
+				{style.italic, 'o'},
+			} {
+				if f.set {
+					buf.WriteRune(char)
+					buf.WriteByte(f.code)
+				}
+			}
+
+			current = style
+		}
+
+		buf.WriteString(text)
+	})
+
+	return buf.String()
+}
+
+func colorIndex(c Color) int {
+	for i, color := range Colors {
+		if color == c {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// walk will call fn with the text of every component in the tree, in order and
+// together with its effective style. Translations found in locale are resolved,
+// their arguments inheriting the style of the translation.
+func walk(m Message, parent legacyStyle, locale map[string]string, fn func(text string, style legacyStyle)) {
+	if m == nil {
+		return
+	}
+	m = unwrap(m)
+
+	base := m.Base()
+	style := parent
+	if base.Color != "" {
+		style.color = base.Color
+	}
+	for _, f := range []struct {
+		v   *bool
+		dst *bool
+	}{
+		{base.Bold, &style.bold},
+		{base.Italic, &style.italic},
+		{base.Underlined, &style.underlined},
+		{base.Strikethrough, &style.strikethrough},
+		{base.Obfuscated, &style.obfuscated},
+	} {
+		if f.v != nil {
+			*f.dst = *f.v
+		}
+	}
+
+	t, ok := m.(*TranslateComponent)
+	format, found := "", false
+	if ok {
+		format, found = locale[t.Translate]
+	}
+
+	if found {
+		for _, part := range translationParts(format, t.With) {
+			walk(part, style, locale, fn)
+		}
+	} else {
+		fn(ownText(m), style)
+	}
+
+	for _, child := range base.Extra {
+		walk(child, style, locale, fn)
+	}
+}
+
+// ownText will return the text of the component itself, without its children.
+func ownText(m Message) string {
+	switch c := m.(type) {
+	case *TextComponent:
+		return c.Text
+	case *TranslateComponent:
+		return c.Translate
+	case *ScoreComponent:
+		return c.Score.Value
+	case *SelectorComponent:
+		return c.Selector
+	case *KeybindComponent:
+		return c.Keybind
+	}
+
+	return ""
+}
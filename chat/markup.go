@@ -0,0 +1,644 @@
+package chat
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The markup format follows MiniMessage: tags such as <red>, <bold> or
+// <hover:show_text:'<gold>hi'> open a style which lasts until the matching
+// closing tag (</red>, </bold>) or the end of the input, and "\<" writes a
+// literal "<". A "<" which does not start a tag, such as in "3 < 5", is kept as
+// text. Supported tags:
+//
+//	<red>, <#FF5555>, <color:red>          colors, also <c:...> and <colour:...>
+//	<bold> <italic> <underlined>           decorations, also <b> <i> <em> <u>
+//	<strikethrough> <obfuscated>           also <st> <obf>, <!bold> turns a decoration off
+//	<gradient:red:#00FF00:blue>            colors the enclosed text with a gradient
+//	<rainbow>                              colors the enclosed text with a rainbow
+//	<click:run_command:'/spawn'>           click events with any ClickAction
+//	<hover:show_text:'text'>               hover events, also show_item:id[:count]
+//	                                       and show_entity:type:uuid[:name]
+//	<insert:text> <font:minecraft:uniform> insertion and font
+//	<lang:key:arg...> <key:key.jump>       translations and keybinds
+//	<selector:@p> <score:name:objective>   selectors and scores
+//	<newline> <br> <reset>                 line breaks, closing every open tag
+
+// MarkupError describes malformed markup. Pos is the byte offset of the problem
+// in the parsed string, also for markup nested in tag arguments.
+type MarkupError struct {
+	Pos int
+	Msg string
+}
+
+func (e *MarkupError) Error() string {
+	return fmt.Sprintf("chat: markup error at position %d: %s", e.Pos, e.Msg)
+}
+
+// markupFrame is an open tag while parsing.
+type markupFrame struct {
+	name string
+	comp *TextComponent
+	pos  int
+
+	// gradient holds the colors of a gradient or rainbow tag.
+	gradient []Color
+	rainbow  bool
+}
+
+// ParseMarkup will parse markup into a component tree.
+func ParseMarkup(s string) (Message, error) {
+	return parseMarkup(s, nil)
+}
+
+// parseMarkup will parse markup, src holding the position of every byte of s
+// and of its end in the string given to ParseMarkup, or nil if s is that
+// string.
+func parseMarkup(s string, src []int) (Message, error) {
+	at := func(i int) int {
+		if src == nil {
+			return i
+		}
+		return src[i]
+	}
+
+	root := &TextComponent{}
+	stack := []*markupFrame{{comp: root}}
+
+	var text strings.Builder
+	flush := func() {
+		if text.Len() == 0 {
+			return
+		}
+
+		top := stack[len(stack)-1].comp
+		if top.Text == "" && len(top.Extra) == 0 && top != root {
+			top.Text = text.String()
+		} else {
+			top.Append(&TextComponent{Text: text.String()})
+		}
+		text.Reset()
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) && (s[i+1] == '<' || s[i+1] == '\\') {
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		}
+		if c != '<' {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			text.WriteRune(r)
+			i += size
+			continue
+		}
+
+		end, ok := tagEnd(s, i)
+		if !ok {
+			text.WriteByte(c)
+			i++
+			continue
+		}
+		tag := s[i+1 : end]
+		flush()
+
+		if strings.HasPrefix(tag, "/") {
+			name := canonicalTag(strings.TrimPrefix(strings.SplitN(tag[1:], ":", 2)[0], "!"))
+			idx := len(stack) - 1
+			for idx > 0 && stack[idx].name != name {
+				idx--
+			}
+			if idx == 0 {
+				return nil, &MarkupError{Pos: at(i), Msg: fmt.Sprintf("closing tag </%s> has no matching opening tag", tag[1:])}
+			}
+
+			for len(stack) > idx {
+				closeFrame(stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+		} else {
+			start := i
+			args, argSrc := splitTagArgs(tag, func(j int) int { return at(start + 1 + j) })
+			frame, self, err := openTag(args, argSrc, at(i))
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case frame == nil && self == nil:
+				// <reset> closes every open tag.
+				for len(stack) > 1 {
+					closeFrame(stack[len(stack)-1])
+					stack = stack[:len(stack)-1]
+				}
+			case self != nil:
+				stack[len(stack)-1].comp.Append(self)
+			default:
+				stack[len(stack)-1].comp.Append(frame.comp)
+				stack = append(stack, frame)
+			}
+		}
+
+		i = end + 1
+	}
+	flush()
+
+	for len(stack) > 1 {
+		closeFrame(stack[len(stack)-1])
+		stack = stack[:len(stack)-1]
+	}
+
+	if len(root.Extra) == 1 && root.Text == "" {
+		return root.Extra[0], nil
+	}
+
+	return root, nil
+}
+
+// tagEnd will return the index of the '>' closing the tag starting at start,
+// and false if the '<' does not start a tag as it is not closed before the
+// next '<' or the end of s, or the tag is empty.
+func tagEnd(s string, start int) (int, bool) {
+	var quote byte
+	for i := start + 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '>':
+			return i, i > start+1
+		case c == '<':
+			return 0, false
+		}
+	}
+
+	return 0, false
+}
+
+// splitTagArgs will split the tag at colons outside quotes and unquote the
+// parts. The positions of the bytes of every part and of its end are
+// returned as well, pos mapping offsets in the tag to the source.
+func splitTagArgs(tag string, pos func(int) int) ([]string, [][]int) {
+	var (
+		args  []string
+		src   [][]int
+		part  strings.Builder
+		where []int
+		quote byte
+	)
+
+	add := func(i int, c byte) {
+		part.WriteByte(c)
+		where = append(where, pos(i))
+	}
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		switch {
+		case quote != 0 && c == '\\' && i+1 < len(tag):
+			i++
+			add(i, tag[i])
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			add(i, c)
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ':':
+			args, src = append(args, part.String()), append(src, append(where, pos(i)))
+			part.Reset()
+			where = nil
+		default:
+			add(i, c)
+		}
+	}
+
+	return append(args, part.String()), append(src, append(where, pos(len(tag))))
+}
+
+// joinArgs will join the tag arguments from i on with colons, as for values
+// which may contain colons, along with the positions of their bytes.
+func joinArgs(args []string, src [][]int, i int) (string, []int) {
+	// The end of every part but the last is the colon after it.
+	var where []int
+	for _, w := range src[i:] {
+		where = append(where, w...)
+	}
+
+	return strings.Join(args[i:], ":"), where
+}
+
+var markupAliases = map[string]string{
+	"b": "bold", "i": "italic", "em": "italic", "u": "underlined", "underline": "underlined",
+	"st": "strikethrough", "obf": "obfuscated", "c": "color", "colour": "color",
+	"br": "newline", "tr": "lang", "translate": "lang", "sel": "selector", "insertion": "insert",
+	"grey": "color", "dark_grey": "color",
+}
+
+// canonicalTag will return the name a tag is closed with.
+func canonicalTag(name string) string {
+	name = strings.ToLower(name)
+	if alias, ok := markupAliases[name]; ok {
+		return alias
+	}
+	if strings.HasPrefix(name, "#") || Color(name).Named() {
+		return "color"
+	}
+
+	return name
+}
+
+// markupColor will parse a color argument, accepting the grey spelling of gray.
+func markupColor(s string) (Color, bool) {
+	s = strings.Replace(strings.ToLower(s), "grey", "gray", 1)
+	c, err := ParseColor(s)
+	return c, err == nil
+}
+
+// openTag will handle an opening tag, returning either a frame for tags
+// enclosing text or a self-closing component. Both are nil for <reset>.
+func openTag(args []string, src [][]int, pos int) (*markupFrame, Message, error) {
+	errorf := func(format string, a ...interface{}) (*markupFrame, Message, error) {
+		return nil, nil, &MarkupError{Pos: pos, Msg: fmt.Sprintf(format, a...)}
+	}
+
+	raw := strings.ToLower(args[0])
+	negate := strings.HasPrefix(raw, "!")
+	name := canonicalTag(strings.TrimPrefix(raw, "!"))
+	frame := &markupFrame{name: name, comp: &TextComponent{}, pos: pos}
+	style := &frame.comp.Component
+
+	switch name {
+	case "bold", "italic", "underlined", "strikethrough", "obfuscated":
+		v := !negate
+		*map[string]**bool{
+			"bold":          &style.Bold,
+			"italic":        &style.Italic,
+			"underlined":    &style.Underlined,
+			"strikethrough": &style.Strikethrough,
+			"obfuscated":    &style.Obfuscated,
+		}[name] = &v
+		return frame, nil, nil
+	case "color":
+		arg := raw
+		if len(args) > 1 {
+			arg = args[1]
+		}
+		color, ok := markupColor(arg)
+		if !ok {
+			return errorf("unknown color %q", arg)
+		}
+		style.Color = color
+		return frame, nil, nil
+	case "gradient", "rainbow":
+		if name == "rainbow" {
+			frame.rainbow = true
+			return frame, nil, nil
+		}
+		for _, arg := range args[1:] {
+			color, ok := markupColor(arg)
+			if !ok {
+				return errorf("unknown gradient color %q", arg)
+			}
+			frame.gradient = append(frame.gradient, color)
+		}
+		if len(frame.gradient) == 0 {
+			frame.gradient = []Color{White, Black}
+		} else if len(frame.gradient) == 1 {
+			return errorf("a gradient needs at least two colors")
+		}
+		return frame, nil, nil
+	case "click":
+		if len(args) != 3 {
+			return errorf("click needs an action and a value")
+		}
+		style.ClickEvent = &ClickEvent{Action: ClickAction(strings.ToLower(args[1])), Value: args[2]}
+		return frame, nil, nil
+	case "hover":
+		if len(args) < 3 {
+			return errorf("hover needs an action and a value")
+		}
+		event, err := markupHover(args[1:], src[1:], pos)
+		if err != nil {
+			return nil, nil, err
+		}
+		style.HoverEvent = event
+		return frame, nil, nil
+	case "insert":
+		if len(args) != 2 {
+			return errorf("insert needs a value")
+		}
+		style.Insertion = args[1]
+		return frame, nil, nil
+	case "font":
+		if len(args) < 2 {
+			return errorf("font needs a value")
+		}
+		style.Font = strings.Join(args[1:], ":")
+		return frame, nil, nil
+	case "reset":
+		return nil, nil, nil
+	case "newline":
+		return nil, &TextComponent{Text: "\n"}, nil
+	case "key":
+		if len(args) != 2 {
+			return errorf("key needs a keybind")
+		}
+		return nil, &KeybindComponent{Keybind: args[1]}, nil
+	case "selector":
+		if len(args) != 2 {
+			return errorf("selector needs a value")
+		}
+		return nil, &SelectorComponent{Selector: args[1]}, nil
+	case "score":
+		if len(args) != 3 {
+			return errorf("score needs a name and an objective")
+		}
+		return nil, &ScoreComponent{Score: Score{Name: args[1], Objective: args[2]}}, nil
+	case "lang":
+		if len(args) < 2 {
+			return errorf("lang needs a translation key")
+		}
+		t := &TranslateComponent{Translate: args[1]}
+		for j, arg := range args[2:] {
+			m, err := parseMarkup(arg, src[2+j])
+			if err != nil {
+				return nil, nil, err
+			}
+			t.With = append(t.With, m)
+		}
+		return nil, t, nil
+	}
+
+	return errorf("unknown tag <%s>", args[0])
+}
+
+func markupHover(args []string, src [][]int, pos int) (*HoverEvent, error) {
+	action := HoverAction(strings.ToLower(args[0]))
+	switch action {
+	case ShowTextHoverAction:
+		m, err := parseMarkup(joinArgs(args, src, 1))
+		if err != nil {
+			return nil, err
+		}
+		return &HoverEvent{Action: action, Text: m}, nil
+	case ShowItem:
+		item := &HoverItem{ID: args[1], Count: 1}
+		// The item ID may itself contain a namespace colon.
+		rest := args[2:]
+		if len(rest) > 0 {
+			if _, err := strconv.Atoi(rest[0]); err != nil {
+				item.ID += ":" + rest[0]
+				rest = rest[1:]
+			}
+		}
+		if len(rest) > 0 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil {
+				return nil, &MarkupError{Pos: pos, Msg: fmt.Sprintf("invalid item count %q", rest[0])}
+			}
+			item.Count = int32(n)
+		}
+		return &HoverEvent{Action: action, Item: item}, nil
+	case ShowEntity:
+		if len(args) < 3 {
+			return nil, &MarkupError{Pos: pos, Msg: "show_entity needs a type and a UUID"}
+		}
+		entity := &HoverEntity{Type: args[1], ID: args[2]}
+		if len(args) > 3 && strings.Count(args[2], "-") != 4 {
+			// A namespaced type is split at its colon.
+			entity.Type, entity.ID, args, src = args[1]+":"+args[2], args[3], args[1:], src[1:]
+		}
+		if len(args) > 3 {
+			m, err := parseMarkup(joinArgs(args, src, 3))
+			if err != nil {
+				return nil, err
+			}
+			entity.Name = m
+		}
+		return &HoverEvent{Action: action, Entity: entity}, nil
+	}
+
+	return nil, &MarkupError{Pos: pos, Msg: fmt.Sprintf("unknown hover action %q", args[0])}
+}
+
+// closeFrame will apply gradients and rainbows once the enclosed text is known.
+func closeFrame(f *markupFrame) {
+	if f.gradient == nil && !f.rainbow {
+		return
+	}
+
+	total := 0
+	countRunes(f.comp, &total)
+	if total == 0 {
+		return
+	}
+
+	index := 0
+	colorize(f.comp, func() Color {
+		var c Color
+		if f.rainbow {
+			c = hue(float64(index) / float64(total))
+		} else {
+			c = gradientAt(f.gradient, index, total)
+		}
+		index++
+		return c
+	})
+}
+
+func countRunes(m Message, total *int) {
+	if t, ok := m.(*TextComponent); ok {
+		*total += utf8.RuneCountInString(t.Text)
+	}
+	for _, child := range m.Base().Extra {
+		if child.Base().Color == "" {
+			countRunes(child, total)
+		}
+	}
+}
+
+// colorize will split the text of the tree into single characters, each
+// colored by next. Children with their own color keep it.
+func colorize(m Message, next func() Color) {
+	base := m.Base()
+	var chars Messages
+	if t, ok := m.(*TextComponent); ok && t.Text != "" {
+		for _, r := range t.Text {
+			c := &TextComponent{Text: string(r)}
+			c.Color = next()
+			chars = append(chars, c)
+		}
+		t.Text = ""
+	}
+
+	for _, child := range base.Extra {
+		if child.Base().Color == "" {
+			colorize(child, next)
+		}
+	}
+
+	if chars != nil {
+		base.Extra = append(chars, base.Extra...)
+	}
+}
+
+func gradientAt(colors []Color, i, total int) Color {
+	t := 0.0
+	if total > 1 {
+		t = float64(i) / float64(total-1)
+	}
+
+	seg := t * float64(len(colors)-1)
+	idx := int(seg)
+	if idx >= len(colors)-1 {
+		idx = len(colors) - 2
+	}
+	frac := seg - float64(idx)
+
+	r1, g1, b1, _ := colors[idx].RGB()
+	r2, g2, b2, _ := colors[idx+1].RGB()
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*frac))
+	}
+
+	return Hex(lerp(r1, r2), lerp(g1, g2), lerp(b1, b2))
+}
+
+// hue will return the fully saturated color at position h (0 to 1) of the color wheel.
+func hue(h float64) Color {
+	h = math.Mod(h, 1) * 6
+	x := uint8(math.Round(255 * (1 - math.Abs(math.Mod(h, 2)-1))))
+
+	switch int(h) {
+	case 0:
+		return Hex(255, x, 0)
+	case 1:
+		return Hex(x, 255, 0)
+	case 2:
+		return Hex(0, 255, x)
+	case 3:
+		return Hex(0, x, 255)
+	case 4:
+		return Hex(x, 0, 255)
+	}
+	return Hex(255, 0, x)
+}
+
+// ToMarkup will write the component tree as markup, the inverse of ParseMarkup.
+func ToMarkup(m Message) string {
+	var buf strings.Builder
+	writeMarkup(&buf, m)
+	return buf.String()
+}
+
+func writeMarkup(buf *strings.Builder, m Message) {
+	if m == nil {
+		return
+	}
+	m = unwrap(m)
+
+	base := m.Base()
+	var closing []string
+	open := func(tag, name string) {
+		buf.WriteString("<" + tag + ">")
+		closing = append(closing, "</"+name+">")
+	}
+
+	if base.Color != "" {
+		if base.Color.Named() {
+			open(string(base.Color), string(base.Color))
+		} else {
+			open(string(base.Color), "color")
+		}
+	}
+	for _, d := range []struct {
+		v    *bool
+		name string
+	}{
+		{base.Bold, "bold"},
+		{base.Italic, "italic"},
+		{base.Underlined, "underlined"},
+		{base.Strikethrough, "strikethrough"},
+		{base.Obfuscated, "obfuscated"},
+	} {
+		if d.v == nil {
+			continue
+		}
+		if *d.v {
+			open(d.name, d.name)
+		} else {
+			open("!"+d.name, d.name)
+		}
+	}
+	if base.Font != "" {
+		open("font:"+base.Font, "font")
+	}
+	if base.Insertion != "" {
+		open("insert:"+quoteMarkup(base.Insertion), "insert")
+	}
+	if e := base.ClickEvent; e != nil {
+		open("click:"+string(e.Action)+":"+quoteMarkup(e.Value), "click")
+	}
+	if e := base.HoverEvent; e != nil {
+		switch {
+		case e.Action == ShowTextHoverAction:
+			open("hover:show_text:"+quoteMarkup(ToMarkup(e.Text)), "hover")
+		case e.Action == ShowItem && e.Item != nil:
+			open(fmt.Sprintf("hover:show_item:%s:%d", e.Item.ID, e.Item.Count), "hover")
+		case e.Action == ShowEntity && e.Entity != nil:
+			tag := "hover:show_entity:" + e.Entity.Type + ":" + e.Entity.ID
+			if e.Entity.Name != nil {
+				tag += ":" + quoteMarkup(ToMarkup(e.Entity.Name))
+			}
+			open(tag, "hover")
+		}
+	}
+
+	switch c := m.(type) {
+	case *TextComponent:
+		buf.WriteString(escapeMarkup(c.Text))
+	case *TranslateComponent:
+		buf.WriteString("<lang:" + quoteMarkup(c.Translate))
+		for _, arg := range c.With {
+			buf.WriteString(":" + quoteMarkup(ToMarkup(arg)))
+		}
+		buf.WriteString(">")
+	case *KeybindComponent:
+		buf.WriteString("<key:" + quoteMarkup(c.Keybind) + ">")
+	case *SelectorComponent:
+		buf.WriteString("<selector:" + quoteMarkup(c.Selector) + ">")
+	case *ScoreComponent:
+		buf.WriteString("<score:" + quoteMarkup(c.Score.Name) + ":" + quoteMarkup(c.Score.Objective) + ">")
+	}
+
+	for _, child := range base.Extra {
+		writeMarkup(buf, child)
+	}
+
+	for i := len(closing) - 1; i >= 0; i-- {
+		buf.WriteString(closing[i])
+	}
+}
+
+// escapeMarkup will escape text so it is not read as tags.
+func escapeMarkup(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "<", `\<`).Replace(s)
+}
+
+// quoteMarkup will quote a tag argument if it holds characters with a meaning inside tags.
+func quoteMarkup(s string) string {
+	if !strings.ContainsAny(s, `:'"<>\ `) && s != "" {
+		return s
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"encoding/json"
+
+	"justanother.org/protocolhelper/nbt"
+)
+
+// MarshalNBT will encode the message as an NBT tag, as sent by the protocol
+// since 1.20.3. Components holding nothing but text become a TAG_String.
+func MarshalNBT(m Message, protocol uint16) interface{} {
+	v := toValue(m, protocol)
+	if obj, ok := v.(map[string]interface{}); ok && len(obj) == 1 {
+		if text, ok := obj["text"].(string); ok {
+			return text
+		}
+	}
+
+	return toNBT(v)
+}
+
+// UnmarshalNBT will decode a message from an NBT tag.
+func UnmarshalNBT(tag interface{}) (Message, error) {
+	return fromValue(fromNBT(tag))
+}
+
+func toNBT(v interface{}) interface{} {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return int8(1)
+		}
+		return int8(0)
+	case int:
+		return int32(val)
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			if n == int64(int32(n)) {
+				return int32(n)
+			}
+			return n
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]interface{}:
+		c := make(nbt.Compound, len(val))
+		for k, item := range val {
+			c[k] = toNBT(item)
+		}
+		return c
+	case []interface{}:
+		list := make(nbt.List, len(val))
+		mixed := false
+		for i, item := range val {
+			list[i] = toNBT(item)
+			if t, _ := nbt.TypeOf(list[i]); i > 0 && t != mustType(list[0]) {
+				mixed = true
+			}
+		}
+		if !mixed {
+			return list
+		}
+
+		// Lists have to hold a single type, so other values are wrapped in a
+		// compound with an empty key, as the vanilla client does.
+		for i, item := range list {
+			if c, ok := item.(nbt.Compound); ok && !(len(c) == 1 && c[""] != nil) {
+				continue
+			}
+			list[i] = nbt.Compound{"": item}
+		}
+		return list
+	}
+
+	return v
+}
+
+func mustType(v interface{}) byte {
+	t, _ := nbt.TypeOf(v)
+	return t
+}
+
+func fromNBT(tag interface{}) interface{} {
+	switch val := tag.(type) {
+	case nbt.Compound:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = fromNBT(item)
+		}
+		return m
+	case nbt.List:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = fromNBT(item)
+		}
+		return list
+	}
+
+	return tag
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ColorMode selects the escape codes used for colors by ANSI.
+type ColorMode int
+
+// Color modes.
+const (
+	ANSI256 ColorMode = iota
+	TrueColor
+)
+
+// PlainText will render the message as text without any formatting.
+// Translations are looked up in locale, missing keys are shown as is.
+func PlainText(m Message, locale map[string]string) string {
+	var buf strings.Builder
+	walk(m, legacyStyle{}, locale, func(text string, style legacyStyle) {
+		buf.WriteString(text)
+	})
+
+	return buf.String()
+}
+
+// ANSI will render the message for terminals, using ANSI escape codes for its
+// colors and decorations. Translations are looked up in locale, obfuscated
+// text is shown as is.
+func ANSI(m Message, locale map[string]string, mode ColorMode) string {
+	var (
+		buf     strings.Builder
+		current legacyStyle
+	)
+
+	walk(m, legacyStyle{}, locale, func(text string, style legacyStyle) {
+		if text == "" {
+			return
+		}
+
+		if style != current {
+			buf.WriteString("\x1b[0")
+			if r, g, b, ok := style.color.RGB(); ok {
+				if mode == TrueColor {
+					buf.WriteString(";38;2;" + strconv.Itoa(int(r)) + ";" + strconv.Itoa(int(g)) + ";" + strconv.Itoa(int(b)))
+				} else {
+					buf.WriteString(";38;5;" + strconv.Itoa(xterm256(r, g, b)))
+				}
+			}
+			for _, f := range []struct {
+				set  bool
+				code string
+			}{
+				{style.bold, ";1"},
+				{style.italic, ";3"},
+				{style.underlined, ";4"},
+				{style.strikethrough, ";9"},
+			} {
+				if f.set {
+					buf.WriteString(f.code)
+				}
+			}
+			buf.WriteByte('m')
+
+			current = style
+		}
+
+		buf.WriteString(text)
+	})
+
+	if current != (legacyStyle{}) {
+		buf.WriteString("\x1b[0m")
+	}
+
+	return buf.String()
+}
+
+// xterm256 will return the index of the xterm 256 color palette closest to the color.
+func xterm256(r, g, b uint8) int {
+	level := func(v uint8) int {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (int(v) - 35) / 40
+	}
+	cube := func(l int) int {
+		if l == 0 {
+			return 0
+		}
+		return 55 + 40*l
+	}
+
+	ri, gi, bi := level(r), level(g), level(b)
+	cubeIdx := 16 + 36*ri + 6*gi + bi
+	cubeDist := sq(cube(ri)-int(r)) + sq(cube(gi)-int(g)) + sq(cube(bi)-int(b))
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayLevel := (avg - 3) / 10
+	if grayLevel < 0 {
+		grayLevel = 0
+	} else if grayLevel > 23 {
+		grayLevel = 23
+	}
+	gray := 8 + 10*grayLevel
+	grayDist := sq(gray-int(r)) + sq(gray-int(g)) + sq(gray-int(b))
+
+	if grayDist < cubeDist {
+		return 232 + grayLevel
+	}
+	return cubeIdx
+}
+
+func sq(v int) int {
+	return v * v
+}
@@ -0,0 +1,85 @@
+package chat
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Translate will create a translation of key, its placeholders filled with args.
+func Translate(key string, args ...Message) *TranslateComponent {
+	return &TranslateComponent{Translate: key, With: args}
+}
+
+// Resolve will return a copy of the message in which every translation found
+// in locale is replaced by a text component holding the translated text and
+// its arguments. Translations with unknown keys are kept.
+func Resolve(m Message, locale map[string]string) Message {
+	return transform(m, func(m Message) Message {
+		c, ok := m.(*TranslateComponent)
+		if !ok {
+			return m
+		}
+
+		format, ok := locale[c.Translate]
+		if !ok {
+			return m
+		}
+
+		t := &TextComponent{Component: c.Component}
+		t.Extra = append(translationParts(format, c.With), c.Extra...)
+		return t
+	})
+}
+
+// translationParts will split a translation format into its literal text and
+// the arguments replacing its %s, %1$s and %% placeholders.
+func translationParts(format string, args Messages) Messages {
+	var (
+		parts Messages
+		text  strings.Builder
+		next  int
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, &TextComponent{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			text.WriteByte(format[i])
+			continue
+		}
+
+		if format[i+1] == '%' {
+			text.WriteByte('%')
+			i++
+			continue
+		}
+
+		idx, end := next, i+1
+		for end < len(format) && format[end] >= '0' && format[end] <= '9' {
+			end++
+		}
+		if end > i+1 && end+1 < len(format) && format[end] == '$' && format[end+1] == 's' {
+			n, _ := strconv.Atoi(format[i+1 : end])
+			idx, end = n-1, end+2
+		} else if format[i+1] == 's' {
+			next++
+			end = i + 2
+		} else {
+			text.WriteByte('%')
+			continue
+		}
+
+		if idx >= 0 && idx < len(args) && args[idx] != nil {
+			flush()
+			parts = append(parts, args[idx])
+		}
+		i = end - 1
+	}
+	flush()
+
+	return parts
+}
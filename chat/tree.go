@@ -0,0 +1,135 @@
+package chat
+
+import "justanother.org/protocolhelper/protocol/version"
+
+// clone will return a shallow copy of the component.
+func clone(m Message) Message {
+	switch c := unwrap(m).(type) {
+	case *TextComponent:
+		t := *c
+		return &t
+	case *TranslateComponent:
+		t := *c
+		return &t
+	case *ScoreComponent:
+		t := *c
+		return &t
+	case *SelectorComponent:
+		t := *c
+		return &t
+	case *KeybindComponent:
+		t := *c
+		return &t
+	case *NBTComponent:
+		t := *c
+		return &t
+	}
+
+	return m
+}
+
+// transform will return a copy of the tree in which every component has been
+// passed through fn, parents before their children. Components for which fn
+// returns nil are removed.
+func transform(m Message, fn func(Message) Message) Message {
+	if m == nil {
+		return nil
+	}
+
+	out := fn(clone(m))
+	if out == nil {
+		return nil
+	}
+
+	switch c := out.(type) {
+	case *TranslateComponent:
+		c.With = transformAll(c.With, fn)
+	case *SelectorComponent:
+		c.Separator = transform(c.Separator, fn)
+	case *NBTComponent:
+		c.Separator = transform(c.Separator, fn)
+	}
+
+	base := out.Base()
+	if base.HoverEvent != nil {
+		e := *base.HoverEvent
+		e.Text = transform(e.Text, fn)
+		if e.Entity != nil {
+			entity := *e.Entity
+			entity.Name = transform(entity.Name, fn)
+			e.Entity = &entity
+		}
+		base.HoverEvent = &e
+	}
+	base.Extra = transformAll(base.Extra, fn)
+	return out
+}
+
+func transformAll(list Messages, fn func(Message) Message) Messages {
+	if list == nil {
+		return nil
+	}
+
+	out := make(Messages, 0, len(list))
+	for _, m := range list {
+		if m = transform(m, fn); m != nil {
+			out = append(out, m)
+		}
+	}
+
+	return out
+}
+
+// Validate will check that every color in the tree is valid.
+func Validate(m Message) error {
+	var err error
+	transform(m, func(m Message) Message {
+		if c := m.Base().Color; err == nil && c != "" && !c.Valid() {
+			err = ErrInvalidColor
+		}
+		return m
+	})
+
+	return err
+}
+
+// ForVersion will return a copy of the message limited to what clients of the
+// protocol version understand. Hex colors become the nearest named color and
+// fonts are dropped before 1.16, newer fields are removed and newer component
+// kinds are replaced by text. Invalid colors are always removed.
+func ForVersion(m Message, protocol uint16) Message {
+	return transform(m, func(m Message) Message {
+		base := m.Base()
+		if !base.Color.Valid() {
+			base.Color = ""
+		}
+		if protocol < version.V1_16 {
+			base.Color = base.Color.Nearest()
+			base.Font = ""
+		}
+		if protocol < version.V1_21_4 {
+			base.ShadowColor = nil
+		}
+
+		switch c := m.(type) {
+		case *TranslateComponent:
+			if protocol < version.V1_19_4 {
+				c.Fallback = ""
+			}
+		case *SelectorComponent:
+			if protocol < version.V1_17 {
+				c.Separator = nil
+			}
+		case *KeybindComponent:
+			if protocol < version.V1_12 {
+				return &TextComponent{Text: c.Keybind, Component: c.Component}
+			}
+		case *NBTComponent:
+			if protocol < version.V1_14 {
+				return &TextComponent{Component: c.Component}
+			}
+		}
+
+		return m
+	})
+}
@@ -0,0 +1,56 @@
+package nbt
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Strings in NBT are stored as Java's "modified UTF-8": the null character is
+// written as two bytes and characters outside the BMP are written as a
+// surrogate pair of three byte sequences.
+
+func encodeMUTF8(s string) []byte {
+	buf := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == 0:
+			buf = append(buf, 0xC0, 0x80)
+		case r < 0x80:
+			buf = append(buf, byte(r))
+		case r < 0x800:
+			buf = append(buf, 0xC0|byte(r>>6), 0x80|byte(r&0x3F))
+		case r < 0x10000:
+			buf = append(buf, 0xE0|byte(r>>12), 0x80|byte((r>>6)&0x3F), 0x80|byte(r&0x3F))
+		default:
+			r1, r2 := utf16.EncodeRune(r)
+			for _, c := range []rune{r1, r2} {
+				buf = append(buf, 0xE0|byte(c>>12), 0x80|byte((c>>6)&0x3F), 0x80|byte(c&0x3F))
+			}
+		}
+	}
+
+	return buf
+}
+
+func decodeMUTF8(b []byte) string {
+	units := make([]uint16, 0, len(b))
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			units = append(units, uint16(c))
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(b):
+			units = append(units, uint16(c&0x1F)<<6|uint16(b[i+1]&0x3F))
+			i += 2
+		case c&0xF0 == 0xE0 && i+2 < len(b):
+			units = append(units, uint16(c&0x0F)<<12|uint16(b[i+1]&0x3F)<<6|uint16(b[i+2]&0x3F))
+			i += 3
+		default:
+			units = append(units, utf8.RuneError)
+			i++
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,183 @@
+// Package nbt implements the Named Binary Tag format used by Minecraft for
+// item data, chunks, registries and world files.
+//
+// Tags are represented with plain Go values:
+//
+//	TAG_Byte       int8
+//	TAG_Short      int16
+//	TAG_Int        int32
+//	TAG_Long       int64
+//	TAG_Float      float32
+//	TAG_Double     float64
+//	TAG_Byte_Array []byte
+//	TAG_String     string
+//	TAG_List       List
+//	TAG_Compound   Compound
+//	TAG_Int_Array  []int32
+//	TAG_Long_Array []int64
+package nbt
+
+import "errors"
+
+// Tag types.
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// maxDepth is the maximum nesting of lists and compounds, the same limit the vanilla client uses.
+const maxDepth = 512
+
+// Possible Errors.
+var (
+	ErrInvalidTagType  = errors.New("nbt: invalid tag type")
+	ErrUnsupportedType = errors.New("nbt: value has no matching tag type")
+	ErrMixedList       = errors.New("nbt: list elements are not all of the same type")
+	ErrTooDeep         = errors.New("nbt: maximum nesting depth exceeded")
+	ErrInvalidLength   = errors.New("nbt: negative or oversized length")
+)
+
+// Compound is a TAG_Compound.
+type Compound map[string]interface{}
+
+// List is a TAG_List. All items have to be of the same type.
+type List []interface{}
+
+// TypeOf will return the tag type for the value v.
+func TypeOf(v interface{}) (byte, error) {
+	switch v.(type) {
+	case nil:
+		return TagEnd, nil
+	case int8:
+		return TagByte, nil
+	case int16:
+		return TagShort, nil
+	case int32:
+		return TagInt, nil
+	case int64:
+		return TagLong, nil
+	case float32:
+		return TagFloat, nil
+	case float64:
+		return TagDouble, nil
+	case []byte:
+		return TagByteArray, nil
+	case string:
+		return TagString, nil
+	case List:
+		return TagList, nil
+	case Compound:
+		return TagCompound, nil
+	case []int32:
+		return TagIntArray, nil
+	case []int64:
+		return TagLongArray, nil
+	}
+
+	return 0, ErrUnsupportedType
+}
+
+// Byte will return the TAG_Byte stored under key.
+func (c Compound) Byte(key string) (int8, bool) {
+	v, ok := c[key].(int8)
+	return v, ok
+}
+
+// Bool will return the TAG_Byte stored under key as a boolean.
+func (c Compound) Bool(key string) (bool, bool) {
+	v, ok := c[key].(int8)
+	return v != 0, ok
+}
+
+// Short will return the TAG_Short stored under key.
+func (c Compound) Short(key string) (int16, bool) {
+	v, ok := c[key].(int16)
+	return v, ok
+}
+
+// Int will return the TAG_Int stored under key.
+func (c Compound) Int(key string) (int32, bool) {
+	v, ok := c[key].(int32)
+	return v, ok
+}
+
+// Long will return the TAG_Long stored under key.
+func (c Compound) Long(key string) (int64, bool) {
+	v, ok := c[key].(int64)
+	return v, ok
+}
+
+// Number will return any integer tag stored under key, regardless of its width.
+func (c Compound) Number(key string) (int64, bool) {
+	switch v := c[key].(type) {
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// Float will return the TAG_Float stored under key.
+func (c Compound) Float(key string) (float32, bool) {
+	v, ok := c[key].(float32)
+	return v, ok
+}
+
+// Double will return the TAG_Double stored under key.
+func (c Compound) Double(key string) (float64, bool) {
+	v, ok := c[key].(float64)
+	return v, ok
+}
+
+// String will return the TAG_String stored under key.
+func (c Compound) String(key string) (string, bool) {
+	v, ok := c[key].(string)
+	return v, ok
+}
+
+// ByteArray will return the TAG_Byte_Array stored under key.
+func (c Compound) ByteArray(key string) ([]byte, bool) {
+	v, ok := c[key].([]byte)
+	return v, ok
+}
+
+// IntArray will return the TAG_Int_Array stored under key.
+func (c Compound) IntArray(key string) ([]int32, bool) {
+	v, ok := c[key].([]int32)
+	return v, ok
+}
+
+// LongArray will return the TAG_Long_Array stored under key.
+func (c Compound) LongArray(key string) ([]int64, bool) {
+	v, ok := c[key].([]int64)
+	return v, ok
+}
+
+// List will return the TAG_List stored under key.
+func (c Compound) List(key string) (List, bool) {
+	v, ok := c[key].(List)
+	return v, ok
+}
+
+// Compound will return the TAG_Compound stored under key.
+func (c Compound) Compound(key string) (Compound, bool) {
+	v, ok := c[key].(Compound)
+	return v, ok
+}
@@ -0,0 +1,215 @@
+package nbt
+
+import (
+	"io"
+
+	"justanother.org/protocolhelper/util"
+)
+
+// maxArrayLength caps the element count of arrays and lists so a corrupt length cannot exhaust memory.
+const maxArrayLength = 1 << 24
+
+// maxPreallocated is the largest number of elements of arrays and lists
+// allocated before they are read. Longer ones grow as elements are read, so a
+// few bytes claiming a long array cannot force a large allocation.
+const maxPreallocated = 1 << 12
+
+// Read will read a named root tag from the reader, as used in files and by the
+// protocol before 1.20.2. A lone TAG_End is returned as a nil tag.
+func Read(r io.Reader) (name string, tag interface{}, err error) {
+	typ, err := util.ReadUint8(r)
+	if err != nil || typ == TagEnd {
+		return
+	}
+
+	if name, err = readString(r); err != nil {
+		return
+	}
+
+	tag, err = readPayload(r, typ, 0)
+	return
+}
+
+// ReadNetwork will read a nameless root tag from the reader, as used by the
+// protocol since 1.20.2. A lone TAG_End is returned as a nil tag.
+func ReadNetwork(r io.Reader) (interface{}, error) {
+	typ, err := util.ReadUint8(r)
+	if err != nil || typ == TagEnd {
+		return nil, err
+	}
+
+	return readPayload(r, typ, 0)
+}
+
+// ReadCompound will read a named root tag and return it as a compound.
+func ReadCompound(r io.Reader) (Compound, error) {
+	_, tag, err := Read(r)
+	if err != nil || tag == nil {
+		return nil, err
+	}
+
+	c, ok := tag.(Compound)
+	if !ok {
+		return nil, ErrInvalidTagType
+	}
+
+	return c, nil
+}
+
+func readString(r io.Reader) (string, error) {
+	length, err := util.ReadUint16(r)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, length)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+
+	return decodeMUTF8(buf), nil
+}
+
+// readLength will read the length of an array or list of elements taking at
+// least size bytes each. Lengths above what is left of readers which tell how
+// much is left, such as *bytes.Reader and *util.Buffer, are rejected.
+func readLength(r io.Reader, size int) (int, error) {
+	length, err := util.ReadInt32(r)
+	if err != nil {
+		return 0, err
+	}
+	if length < 0 || length > maxArrayLength {
+		return 0, ErrInvalidLength
+	}
+	if l, ok := r.(interface{ Len() int }); ok && int(length)*size > l.Len() {
+		return 0, ErrInvalidLength
+	}
+
+	return int(length), nil
+}
+
+// preallocated will return the number of elements to allocate for an array
+// or list of the length.
+func preallocated(length int) int {
+	if length > maxPreallocated {
+		return maxPreallocated
+	}
+
+	return length
+}
+
+func readPayload(r io.Reader, typ byte, depth int) (interface{}, error) {
+	switch typ {
+	case TagByte:
+		return util.ReadInt8(r)
+	case TagShort:
+		return util.ReadInt16(r)
+	case TagInt:
+		return util.ReadInt32(r)
+	case TagLong:
+		return util.ReadInt64(r)
+	case TagFloat:
+		return util.ReadFloat32(r)
+	case TagDouble:
+		return util.ReadFloat64(r)
+	case TagString:
+		return readString(r)
+	case TagByteArray:
+		length, err := readLength(r, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		// The array is read in parts of at most maxPreallocated bytes.
+		buf := make([]byte, 0, preallocated(length))
+		for len(buf) < length {
+			n := preallocated(length - len(buf))
+			buf = append(buf, make([]byte, n)...)
+			if _, err = io.ReadFull(r, buf[len(buf)-n:]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case TagIntArray:
+		length, err := readLength(r, 4)
+		if err != nil {
+			return nil, err
+		}
+
+		arr := make([]int32, 0, preallocated(length))
+		for i := 0; i < length; i++ {
+			v, err := util.ReadInt32(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case TagLongArray:
+		length, err := readLength(r, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		arr := make([]int64, 0, preallocated(length))
+		for i := 0; i < length; i++ {
+			v, err := util.ReadInt64(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case TagList:
+		if depth >= maxDepth {
+			return nil, ErrTooDeep
+		}
+
+		elem, err := util.ReadUint8(r)
+		if err != nil {
+			return nil, err
+		}
+		length, err := readLength(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		if elem == TagEnd && length > 0 {
+			return nil, ErrInvalidTagType
+		}
+
+		list := make(List, 0, preallocated(length))
+		for i := 0; i < length; i++ {
+			v, err := readPayload(r, elem, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case TagCompound:
+		if depth >= maxDepth {
+			return nil, ErrTooDeep
+		}
+
+		c := make(Compound)
+		for {
+			typ, err := util.ReadUint8(r)
+			if err != nil {
+				return nil, err
+			}
+			if typ == TagEnd {
+				return c, nil
+			}
+
+			name, err := readString(r)
+			if err != nil {
+				return nil, err
+			}
+			if c[name], err = readPayload(r, typ, depth+1); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, ErrInvalidTagType
+}
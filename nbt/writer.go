@@ -0,0 +1,160 @@
+package nbt
+
+import (
+	"io"
+	"sort"
+
+	"justanother.org/protocolhelper/util"
+)
+
+// Write will write tag as a named root tag, as used in files and by the
+// protocol before 1.20.2. A nil tag is written as a lone TAG_End.
+func Write(w io.Writer, name string, tag interface{}) error {
+	typ, err := TypeOf(tag)
+	if err != nil {
+		return err
+	}
+	if err = util.WriteUint8(w, typ); err != nil || typ == TagEnd {
+		return err
+	}
+	if err = writeString(w, name); err != nil {
+		return err
+	}
+
+	return writePayload(w, tag, 0)
+}
+
+// WriteNetwork will write tag as a nameless root tag, as used by the protocol
+// since 1.20.2. A nil tag is written as a lone TAG_End.
+func WriteNetwork(w io.Writer, tag interface{}) error {
+	typ, err := TypeOf(tag)
+	if err != nil {
+		return err
+	}
+	if err = util.WriteUint8(w, typ); err != nil || typ == TagEnd {
+		return err
+	}
+
+	return writePayload(w, tag, 0)
+}
+
+func writeString(w io.Writer, s string) error {
+	buf := encodeMUTF8(s)
+	if len(buf) > 0xFFFF {
+		return ErrInvalidLength
+	}
+	if err := util.WriteUint16(w, uint16(len(buf))); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func writePayload(w io.Writer, tag interface{}, depth int) error {
+	switch v := tag.(type) {
+	case int8:
+		return util.WriteInt8(w, v)
+	case int16:
+		return util.WriteInt16(w, v)
+	case int32:
+		return util.WriteInt32(w, v)
+	case int64:
+		return util.WriteInt64(w, v)
+	case float32:
+		return util.WriteFloat32(w, v)
+	case float64:
+		return util.WriteFloat64(w, v)
+	case string:
+		return writeString(w, v)
+	case []byte:
+		if err := util.WriteInt32(w, int32(len(v))); err != nil {
+			return err
+		}
+
+		_, err := w.Write(v)
+		return err
+	case []int32:
+		if err := util.WriteInt32(w, int32(len(v))); err != nil {
+			return err
+		}
+		for _, i := range v {
+			if err := util.WriteInt32(w, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []int64:
+		if err := util.WriteInt32(w, int32(len(v))); err != nil {
+			return err
+		}
+		for _, i := range v {
+			if err := util.WriteInt64(w, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case List:
+		if depth >= maxDepth {
+			return ErrTooDeep
+		}
+
+		elem := TagEnd
+		if len(v) > 0 {
+			var err error
+			if elem, err = TypeOf(v[0]); err != nil {
+				return err
+			}
+		}
+		if err := util.WriteUint8(w, elem); err != nil {
+			return err
+		}
+		if err := util.WriteInt32(w, int32(len(v))); err != nil {
+			return err
+		}
+
+		for _, item := range v {
+			if typ, err := TypeOf(item); err != nil || typ != elem {
+				return ErrMixedList
+			}
+			if err := writePayload(w, item, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Compound:
+		if depth >= maxDepth {
+			return ErrTooDeep
+		}
+
+		// Keys are sorted so the same compound always produces the same bytes.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			typ, err := TypeOf(v[k])
+			if err != nil {
+				return err
+			}
+			if typ == TagEnd {
+				continue
+			}
+			if err = util.WriteUint8(w, typ); err != nil {
+				return err
+			}
+			if err = writeString(w, k); err != nil {
+				return err
+			}
+			if err = writePayload(w, v[k], depth+1); err != nil {
+				return err
+			}
+		}
+
+		return util.WriteUint8(w, TagEnd)
+	}
+
+	return ErrUnsupportedType
+}
@@ -0,0 +1,171 @@
+package codecs
+
+import (
+	"io"
+
+	"justanother.org/protocolhelper/util"
+)
+
+// BitSet is the codec for variable length bit sets, sent as a VarInt count
+// followed by that many longs. Bit i is stored in long i/64.
+type BitSet []int64
+
+// Get will report whether bit i is set.
+func (b BitSet) Get(i int) bool {
+	if i < 0 || i/64 >= len(b) {
+		return false
+	}
+
+	return b[i/64]&(1<<uint(i%64)) != 0
+}
+
+// Set will set or clear bit i, growing the set as needed.
+func (b *BitSet) Set(i int, v bool) {
+	if i < 0 {
+		return
+	}
+
+	if i/64 >= len(*b) {
+		if !v {
+			return
+		}
+		*b = append(*b, make(BitSet, i/64+1-len(*b))...)
+	}
+
+	if v {
+		(*b)[i/64] |= 1 << uint(i%64)
+	} else {
+		(*b)[i/64] &^= 1 << uint(i%64)
+	}
+}
+
+// Len will return the index of the highest set bit plus one.
+func (b BitSet) Len() int {
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] == 0 {
+			continue
+		}
+		for bit := 63; bit >= 0; bit-- {
+			if b[i]&(1<<uint(bit)) != 0 {
+				return i*64 + bit + 1
+			}
+		}
+	}
+
+	return 0
+}
+
+// Decode will decode the type
+func (b BitSet) Decode(r io.Reader) (interface{}, error) {
+	l, err := util.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if l < 0 || l > 1<<16 {
+		return nil, ErrInvalidLength
+	}
+
+	set := make(BitSet, l)
+	for i := range set {
+		if set[i], err = util.ReadInt64(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return set, nil
+}
+
+// Encode will encode the type
+func (b BitSet) Encode(w io.Writer) error {
+	if err := util.WriteVarInt(w, len(b)); err != nil {
+		return err
+	}
+
+	for _, l := range b {
+		if err := util.WriteInt64(w, l); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FixedBitSet is the codec for bit sets of a size known from the packet
+// layout, sent as ceil(size/8) bytes. Bit i is stored in byte i/8. The size of
+// a packet field is given with the `mc:"size=N"` struct tag.
+type FixedBitSet struct {
+	size int
+	data []byte
+}
+
+// NewFixedBitSet will create an empty bit set holding size bits.
+func NewFixedBitSet(size int) FixedBitSet {
+	if size < 0 {
+		size = 0
+	}
+
+	return FixedBitSet{size: size, data: make([]byte, (size+7)/8)}
+}
+
+// WithSize will return an empty bit set of the given size.
+func (b FixedBitSet) WithSize(size int) Codec {
+	return NewFixedBitSet(size)
+}
+
+// Get will report whether bit i is set.
+func (b FixedBitSet) Get(i int) bool {
+	if i < 0 || i >= b.size {
+		return false
+	}
+
+	return b.data[i/8]&(1<<uint(i%8)) != 0
+}
+
+// Set will set or clear bit i. Bits outside the set are ignored.
+func (b FixedBitSet) Set(i int, v bool) {
+	if i < 0 || i >= b.size {
+		return
+	}
+
+	if v {
+		b.data[i/8] |= 1 << uint(i%8)
+	} else {
+		b.data[i/8] &^= 1 << uint(i%8)
+	}
+}
+
+// Len will return the number of bits in the set.
+func (b FixedBitSet) Len() int {
+	return b.size
+}
+
+// Length will return the index of the highest set bit plus one, counting the
+// padding bits of the last byte, which a peer may have set.
+func (b FixedBitSet) Length() int {
+	for i := len(b.data) - 1; i >= 0; i-- {
+		for bit := 7; bit >= 0; bit-- {
+			if b.data[i]&(1<<uint(bit)) != 0 {
+				return i*8 + bit + 1
+			}
+		}
+	}
+
+	return 0
+}
+
+// Decode will decode the type
+func (b FixedBitSet) Decode(r io.Reader) (interface{}, error) {
+	set := NewFixedBitSet(b.size)
+	_, err := io.ReadFull(r, set.data)
+	return set, err
+}
+
+// Encode will encode the type
+func (b FixedBitSet) Encode(w io.Writer) error {
+	if len(b.data) != (b.size+7)/8 {
+		return ErrInvalidLength
+	}
+
+	_, err := w.Write(b.data)
+	return err
+}
@@ -0,0 +1,63 @@
+package codecs
+
+import (
+	"io"
+
+	"justanother.org/protocolhelper/chat"
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// Chat is the codec for chat components. They are sent as a JSON string
+// before 1.20.3 and as a nameless NBT tag after.
+type Chat struct {
+	Message chat.Message
+}
+
+// Decode will decode the type
+func (c Chat) Decode(r io.Reader) (interface{}, error) {
+	return c.DecodeVersion(r, version.Latest)
+}
+
+// Encode will encode the type
+func (c Chat) Encode(w io.Writer) error {
+	return c.EncodeVersion(w, version.Latest)
+}
+
+// DecodeVersion will decode the type for the protocol version
+func (c Chat) DecodeVersion(r io.Reader, protocol uint16) (interface{}, error) {
+	if protocol >= version.V1_20_3 {
+		tag, err := nbt.ReadNetwork(r)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := chat.UnmarshalNBT(tag)
+		return Chat{Message: m}, err
+	}
+
+	s, err := util.ReadStringMax(r, MaxJSONLength)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := chat.Unmarshal([]byte(s))
+	return Chat{Message: m}, err
+}
+
+// EncodeVersion will encode the type for the protocol version. The message
+// is first limited to what clients of the version understand.
+func (c Chat) EncodeVersion(w io.Writer, protocol uint16) error {
+	m := chat.ForVersion(c.Message, protocol)
+	if protocol >= version.V1_20_3 {
+		return nbt.WriteNetwork(w, chat.MarshalNBT(m, protocol))
+	}
+
+	data, err := chat.MarshalVersion(m, protocol)
+	if err != nil {
+		return err
+	}
+
+	return util.WriteStringMax(w, string(data), MaxJSONLength)
+}
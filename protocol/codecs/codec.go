@@ -5,8 +5,13 @@ import (
 	"io"
 )
 
-// ErrUnknownCodecType is an error that happens when there is not a codec for that type.
-var ErrUnknownCodecType = errors.New("unknown codec type")
+// Possible Errors.
+var (
+	// ErrUnknownCodecType is an error that happens when there is not a codec for that type.
+	ErrUnknownCodecType = errors.New("unknown codec type")
+	// ErrInvalidLength is an error that happens when a length is negative or too large.
+	ErrInvalidLength = errors.New("invalid length")
+)
 
 // Codec is an interface for all supported Codecs
 // Any packet to be encoded or decoded should have its types consist of codecs
@@ -14,3 +19,29 @@ type Codec interface {
 	Decode(r io.Reader) (interface{}, error)
 	Encode(w io.Writer) error
 }
+
+// VersionedCodec is implemented by codecs whose wire format depends on the
+// protocol version of the connection. Their Decode and Encode methods use
+// version.Latest.
+type VersionedCodec interface {
+	Codec
+	DecodeVersion(r io.Reader, protocol uint16) (interface{}, error)
+	EncodeVersion(w io.Writer, protocol uint16) error
+}
+
+// LimitedCodec is implemented by codecs with a maximum length, which a
+// packet field can change with the `mc:"max=N"` struct tag. Values over the
+// maximum are rejected before they are read.
+type LimitedCodec interface {
+	Codec
+	DecodeMax(r io.Reader, max int) (interface{}, error)
+	EncodeMax(w io.Writer, max int) error
+}
+
+// SizedCodec is implemented by codecs whose length is fixed by the packet
+// layout instead of being sent on the wire.
+type SizedCodec interface {
+	Codec
+	Len() int
+	WithSize(size int) Codec
+}
@@ -0,0 +1,150 @@
+package codecs
+
+import (
+	"errors"
+	"io"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// ErrUnknownHeightmap is returned for heightmaps which have no ID in the protocol version.
+var ErrUnknownHeightmap = errors.New("unknown heightmap type")
+
+// HeightmapTypes are the heightmap names in the order of their IDs, which
+// are sent instead of their names since 1.21.5.
+var HeightmapTypes = []string{
+	"WORLD_SURFACE_WG",
+	"WORLD_SURFACE",
+	"OCEAN_FLOOR_WG",
+	"OCEAN_FLOOR",
+	"MOTION_BLOCKING",
+	"MOTION_BLOCKING_NO_LEAVES",
+}
+
+// Heightmaps is the codec for the heightmaps of a chunk, packed long arrays
+// by their name. They are sent as an NBT compound before 1.21.5 and as a
+// list of IDs and long arrays after.
+type Heightmaps map[string][]int64
+
+// Decode will decode the type
+func (h Heightmaps) Decode(r io.Reader) (interface{}, error) {
+	return h.DecodeVersion(r, version.Latest)
+}
+
+// Encode will encode the type
+func (h Heightmaps) Encode(w io.Writer) error {
+	return h.EncodeVersion(w, version.Latest)
+}
+
+// DecodeVersion will decode the type for the protocol version
+func (h Heightmaps) DecodeVersion(r io.Reader, protocol uint16) (interface{}, error) {
+	if protocol < version.V1_21_5 {
+		tag, err := readNBT(r, protocol)
+		if err != nil {
+			return nil, err
+		}
+
+		c, _ := tag.(nbt.Compound)
+		maps := make(Heightmaps, len(c))
+		for name := range c {
+			if data, ok := c.LongArray(name); ok {
+				maps[name] = data
+			}
+		}
+		return maps, nil
+	}
+
+	count, err := util.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 || count > len(HeightmapTypes) {
+		return nil, ErrInvalidLength
+	}
+
+	maps := make(Heightmaps, count)
+	for i := 0; i < count; i++ {
+		id, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if id < 0 || id >= len(HeightmapTypes) {
+			return nil, ErrUnknownHeightmap
+		}
+
+		l, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		// 256 entries of at most 32 bits each.
+		if l < 0 || l > 128 {
+			return nil, ErrInvalidLength
+		}
+
+		data := make([]int64, l)
+		for j := range data {
+			if data[j], err = util.ReadInt64(r); err != nil {
+				return nil, err
+			}
+		}
+		maps[HeightmapTypes[id]] = data
+	}
+
+	return maps, nil
+}
+
+// EncodeVersion will encode the type for the protocol version
+func (h Heightmaps) EncodeVersion(w io.Writer, protocol uint16) error {
+	if protocol < version.V1_21_5 {
+		c := make(nbt.Compound, len(h))
+		for name, data := range h {
+			c[name] = data
+		}
+		return writeNBT(w, c, protocol)
+	}
+
+	for name := range h {
+		if heightmapID(name) < 0 {
+			return ErrUnknownHeightmap
+		}
+	}
+
+	err := util.WriteVarInt(w, len(h))
+	if err != nil {
+		return err
+	}
+
+	// Written in the order of their IDs, so the output does not depend on map order.
+	for id, name := range HeightmapTypes {
+		data, ok := h[name]
+		if !ok {
+			continue
+		}
+
+		if err = util.WriteVarInt(w, id); err != nil {
+			return err
+		}
+		if err = util.WriteVarInt(w, len(data)); err != nil {
+			return err
+		}
+		for _, v := range data {
+			if err = util.WriteInt64(w, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func heightmapID(name string) int {
+	for id, n := range HeightmapTypes {
+		if n == name {
+			return id
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,535 @@
+package codecs
+
+import (
+	"errors"
+	"io"
+	"sort"
+
+	"justanother.org/protocolhelper/chat"
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// Possible entity metadata errors.
+var (
+	ErrUnknownMetadataType  = errors.New("unknown entity metadata type")
+	ErrInvalidMetadata      = errors.New("entity metadata value does not match its type")
+	ErrInvalidMetadataIndex = errors.New("entity metadata index 0xFF is reserved for the terminator")
+	ErrUnknownParticle      = errors.New("unknown particle type")
+)
+
+// MetadataType is the kind of value held by an entity metadata entry. The
+// numeric IDs sent on the wire depend on the protocol version and are looked
+// up in MetadataTypes.
+type MetadataType int
+
+// Entity metadata types and the Go type of their values. Optional types hold
+// a nil Value when absent.
+const (
+	MetaByte             MetadataType = iota // int8
+	MetaVarInt                               // int32
+	MetaVarLong                              // int64
+	MetaFloat                                // float32
+	MetaString                               // string
+	MetaChat                                 // chat.Message
+	MetaOptChat                              // as MetaChat
+	MetaSlot                                 // Slot
+	MetaBoolean                              // bool
+	MetaRotation                             // Rotation
+	MetaPosition                             // Position
+	MetaOptPosition                          // Position
+	MetaDirection                            // int32
+	MetaOptUUID                              // UUID
+	MetaBlockState                           // int32
+	MetaOptBlockState                        // int32, absent for air
+	MetaNBT                                  // NBT tag value
+	MetaParticle                             // Particle
+	MetaParticles                            // []Particle
+	MetaVillagerData                         // VillagerData
+	MetaOptVarInt                            // int32
+	MetaPose                                 // int32
+	MetaCatVariant                           // int32
+	MetaCowVariant                           // int32
+	MetaWolfVariant                          // int32
+	MetaWolfSoundVariant                     // int32
+	MetaFrogVariant                          // int32
+	MetaPigVariant                           // int32
+	MetaChickenVariant                       // int32
+	MetaOptGlobalPos                         // GlobalPos
+	MetaPaintingVariant                      // int32
+	MetaSnifferState                         // int32
+	MetaArmadilloState                       // int32
+	MetaVector3                              // Vector3
+	MetaQuaternion                           // Quaternion
+)
+
+// MetadataTypes lists the metadata type IDs by protocol version, newest first.
+// The entry used is the first whose Protocol is not above the connection's.
+var MetadataTypes = []struct {
+	Protocol uint16
+	Types    []MetadataType
+}{
+	{version.V1_21_5, []MetadataType{
+		MetaByte, MetaVarInt, MetaVarLong, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot,
+		MetaBoolean, MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID,
+		MetaBlockState, MetaOptBlockState, MetaNBT, MetaParticle, MetaParticles, MetaVillagerData,
+		MetaOptVarInt, MetaPose, MetaCatVariant, MetaCowVariant, MetaWolfVariant, MetaWolfSoundVariant,
+		MetaFrogVariant, MetaPigVariant, MetaChickenVariant, MetaOptGlobalPos, MetaPaintingVariant,
+		MetaSnifferState, MetaArmadilloState, MetaVector3, MetaQuaternion,
+	}},
+	{version.V1_20_5, []MetadataType{
+		MetaByte, MetaVarInt, MetaVarLong, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot,
+		MetaBoolean, MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID,
+		MetaBlockState, MetaOptBlockState, MetaNBT, MetaParticle, MetaParticles, MetaVillagerData,
+		MetaOptVarInt, MetaPose, MetaCatVariant, MetaWolfVariant, MetaFrogVariant, MetaOptGlobalPos,
+		MetaPaintingVariant, MetaSnifferState, MetaArmadilloState, MetaVector3, MetaQuaternion,
+	}},
+	{version.V1_19_4, []MetadataType{
+		MetaByte, MetaVarInt, MetaVarLong, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot,
+		MetaBoolean, MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID,
+		MetaBlockState, MetaOptBlockState, MetaNBT, MetaParticle, MetaVillagerData, MetaOptVarInt,
+		MetaPose, MetaCatVariant, MetaFrogVariant, MetaOptGlobalPos, MetaPaintingVariant,
+		MetaSnifferState, MetaVector3, MetaQuaternion,
+	}},
+	{version.V1_19_3, []MetadataType{
+		MetaByte, MetaVarInt, MetaVarLong, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot,
+		MetaBoolean, MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID,
+		MetaOptBlockState, MetaNBT, MetaParticle, MetaVillagerData, MetaOptVarInt, MetaPose,
+		MetaCatVariant, MetaFrogVariant, MetaOptGlobalPos, MetaPaintingVariant,
+	}},
+	{version.V1_19, []MetadataType{
+		MetaByte, MetaVarInt, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot, MetaBoolean,
+		MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID, MetaOptBlockState,
+		MetaNBT, MetaParticle, MetaVillagerData, MetaOptVarInt, MetaPose, MetaCatVariant,
+		MetaFrogVariant, MetaOptGlobalPos, MetaPaintingVariant,
+	}},
+	{version.V1_14, []MetadataType{
+		MetaByte, MetaVarInt, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot, MetaBoolean,
+		MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID, MetaOptBlockState,
+		MetaNBT, MetaParticle, MetaVillagerData, MetaOptVarInt, MetaPose,
+	}},
+	{version.V1_13, []MetadataType{
+		MetaByte, MetaVarInt, MetaFloat, MetaString, MetaChat, MetaOptChat, MetaSlot, MetaBoolean,
+		MetaRotation, MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID, MetaOptBlockState,
+		MetaNBT, MetaParticle,
+	}},
+	{version.V1_9, []MetadataType{
+		MetaByte, MetaVarInt, MetaFloat, MetaString, MetaChat, MetaSlot, MetaBoolean, MetaRotation,
+		MetaPosition, MetaOptPosition, MetaDirection, MetaOptUUID, MetaOptBlockState, MetaNBT,
+	}},
+}
+
+// Rotation is the value of a MetaRotation entry, in degrees.
+type Rotation struct {
+	X, Y, Z float32
+}
+
+// VillagerData is the value of a MetaVillagerData entry.
+type VillagerData struct {
+	Type, Profession, Level int32
+}
+
+// GlobalPos is the value of a MetaOptGlobalPos entry.
+type GlobalPos struct {
+	Dimension string
+	Position  Position
+}
+
+// Vector3 is the value of a MetaVector3 entry.
+type Vector3 struct {
+	X, Y, Z float32
+}
+
+// Quaternion is the value of a MetaQuaternion entry.
+type Quaternion struct {
+	X, Y, Z, W float32
+}
+
+// Metadata is a single entity metadata entry.
+type Metadata struct {
+	Type  MetadataType
+	Value interface{}
+}
+
+// EntityMetadata is the codec for entity metadata, a set of entries keyed by
+// their index and terminated by 0xFF on the wire.
+type EntityMetadata map[uint8]Metadata
+
+// Set will set the entry at index.
+func (m EntityMetadata) Set(index uint8, typ MetadataType, value interface{}) {
+	m[index] = Metadata{Type: typ, Value: value}
+}
+
+// Decode will decode the type
+func (m EntityMetadata) Decode(r io.Reader) (interface{}, error) {
+	return m.DecodeVersion(r, version.Latest)
+}
+
+// Encode will encode the type
+func (m EntityMetadata) Encode(w io.Writer) error {
+	return m.EncodeVersion(w, version.Latest)
+}
+
+// DecodeVersion will decode the type for the protocol version
+func (m EntityMetadata) DecodeVersion(r io.Reader, protocol uint16) (interface{}, error) {
+	types := metadataTypes(protocol)
+	if types == nil {
+		return nil, ErrUnknownMetadataType
+	}
+
+	meta := make(EntityMetadata)
+	for {
+		index, err := util.ReadUint8(r)
+		if err != nil {
+			return nil, err
+		}
+		if index == 0xFF {
+			return meta, nil
+		}
+
+		id, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if id < 0 || id >= len(types) {
+			return nil, ErrUnknownMetadataType
+		}
+
+		value, err := readMetadataValue(r, types[id], protocol)
+		if err != nil {
+			return nil, err
+		}
+
+		meta[index] = Metadata{Type: types[id], Value: value}
+	}
+}
+
+// EncodeVersion will encode the type for the protocol version
+func (m EntityMetadata) EncodeVersion(w io.Writer, protocol uint16) error {
+	types := metadataTypes(protocol)
+	if types == nil {
+		return ErrUnknownMetadataType
+	}
+	if _, ok := m[0xFF]; ok {
+		return ErrInvalidMetadataIndex
+	}
+
+	indexes := make([]int, 0, len(m))
+	for index := range m {
+		indexes = append(indexes, int(index))
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		entry := m[uint8(index)]
+
+		id := -1
+		for i, typ := range types {
+			if typ == entry.Type {
+				id = i
+				break
+			}
+		}
+		if id < 0 {
+			return ErrUnknownMetadataType
+		}
+
+		if err := util.WriteUint8(w, uint8(index)); err != nil {
+			return err
+		}
+		if err := util.WriteVarInt(w, id); err != nil {
+			return err
+		}
+		if err := writeMetadataValue(w, entry, protocol); err != nil {
+			return err
+		}
+	}
+
+	return util.WriteUint8(w, 0xFF)
+}
+
+func metadataTypes(protocol uint16) []MetadataType {
+	for _, table := range MetadataTypes {
+		if protocol >= table.Protocol {
+			return table.Types
+		}
+	}
+
+	return nil
+}
+
+// isVarIntType reports whether entries of the type are plain VarInts.
+func isVarIntType(typ MetadataType) bool {
+	switch typ {
+	case MetaVarInt, MetaDirection, MetaBlockState, MetaPose, MetaCatVariant, MetaCowVariant,
+		MetaWolfVariant, MetaWolfSoundVariant, MetaFrogVariant, MetaPigVariant, MetaChickenVariant,
+		MetaPaintingVariant, MetaSnifferState, MetaArmadilloState:
+		return true
+	}
+
+	return false
+}
+
+func readChat(r io.Reader, protocol uint16) (interface{}, error) {
+	c, err := Chat{}.DecodeVersion(r, protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.(Chat).Message, nil
+}
+
+func writeChat(w io.Writer, value interface{}, protocol uint16) error {
+	m, ok := value.(chat.Message)
+	if !ok {
+		return ErrInvalidMetadata
+	}
+
+	return Chat{Message: m}.EncodeVersion(w, protocol)
+}
+
+func readFloats(r io.Reader, dst ...*float32) (err error) {
+	for _, f := range dst {
+		if *f, err = util.ReadFloat32(r); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func writeFloats(w io.Writer, src ...float32) error {
+	for _, f := range src {
+		if err := util.WriteFloat32(w, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readMetadataValue(r io.Reader, typ MetadataType, protocol uint16) (interface{}, error) {
+	if isVarIntType(typ) {
+		v, err := util.ReadVarInt(r)
+		return int32(v), err
+	}
+
+	switch typ {
+	case MetaByte:
+		return util.ReadInt8(r)
+	case MetaVarLong:
+		return util.ReadVarLong(r)
+	case MetaFloat:
+		return util.ReadFloat32(r)
+	case MetaString:
+		return util.ReadString(r)
+	case MetaChat:
+		return readChat(r, protocol)
+	case MetaSlot:
+		return Slot{}.DecodeVersion(r, protocol)
+	case MetaBoolean:
+		return util.ReadBool(r)
+	case MetaRotation:
+		var v Rotation
+		err := readFloats(r, &v.X, &v.Y, &v.Z)
+		return v, err
+	case MetaVector3:
+		var v Vector3
+		err := readFloats(r, &v.X, &v.Y, &v.Z)
+		return v, err
+	case MetaQuaternion:
+		var v Quaternion
+		err := readFloats(r, &v.X, &v.Y, &v.Z, &v.W)
+		return v, err
+	case MetaPosition:
+		return Position{}.DecodeVersion(r, protocol)
+	case MetaNBT:
+		return readNBT(r, protocol)
+	case MetaParticle:
+		return readParticle(r, protocol)
+	case MetaParticles:
+		n, err := util.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrInvalidLength
+		}
+
+		var particles []Particle
+		for i := 0; i < n; i++ {
+			p, err := readParticle(r, protocol)
+			if err != nil {
+				return nil, err
+			}
+			particles = append(particles, p)
+		}
+		return particles, nil
+	case MetaVillagerData:
+		var v [3]int
+		for i := range v {
+			var err error
+			if v[i], err = util.ReadVarInt(r); err != nil {
+				return nil, err
+			}
+		}
+		return VillagerData{Type: int32(v[0]), Profession: int32(v[1]), Level: int32(v[2])}, nil
+	case MetaOptBlockState, MetaOptVarInt:
+		v, err := util.ReadVarInt(r)
+		if err != nil || v == 0 {
+			return nil, err
+		}
+		if typ == MetaOptVarInt {
+			v--
+		}
+		return int32(v), nil
+	case MetaOptChat, MetaOptPosition, MetaOptUUID, MetaOptGlobalPos:
+		present, err := util.ReadBool(r)
+		if err != nil || !present {
+			return nil, err
+		}
+
+		switch typ {
+		case MetaOptChat:
+			return readChat(r, protocol)
+		case MetaOptPosition:
+			return Position{}.DecodeVersion(r, protocol)
+		case MetaOptUUID:
+			return UUID{}.Decode(r)
+		}
+
+		dim, err := util.ReadString(r)
+		if err != nil {
+			return nil, err
+		}
+		pos, err := Position{}.DecodeVersion(r, protocol)
+		if err != nil {
+			return nil, err
+		}
+		return GlobalPos{Dimension: dim, Position: pos.(Position)}, nil
+	}
+
+	return nil, ErrUnknownMetadataType
+}
+
+func writeMetadataValue(w io.Writer, entry Metadata, protocol uint16) error {
+	switch entry.Type {
+	case MetaOptBlockState, MetaOptVarInt:
+		if entry.Value == nil {
+			return util.WriteVarInt(w, 0)
+		}
+
+		v, ok := entry.Value.(int32)
+		if !ok {
+			return ErrInvalidMetadata
+		}
+		if entry.Type == MetaOptVarInt {
+			v++
+		}
+		return util.WriteVarInt(w, int(v))
+	case MetaOptChat, MetaOptPosition, MetaOptUUID, MetaOptGlobalPos:
+		if err := util.WriteBool(w, entry.Value != nil); err != nil || entry.Value == nil {
+			return err
+		}
+
+		switch entry.Type {
+		case MetaOptChat:
+			return writeChat(w, entry.Value, protocol)
+		case MetaOptPosition:
+			entry.Type = MetaPosition
+		case MetaOptUUID:
+			v, ok := entry.Value.(UUID)
+			if !ok {
+				return ErrInvalidMetadata
+			}
+			return v.Encode(w)
+		case MetaOptGlobalPos:
+			v, ok := entry.Value.(GlobalPos)
+			if !ok {
+				return ErrInvalidMetadata
+			}
+			if err := util.WriteString(w, v.Dimension); err != nil {
+				return err
+			}
+			return v.Position.EncodeVersion(w, protocol)
+		}
+	case MetaChat:
+		return writeChat(w, entry.Value, protocol)
+	case MetaNBT:
+		return writeNBT(w, entry.Value, protocol)
+	}
+
+	switch v := entry.Value.(type) {
+	case int8:
+		if entry.Type == MetaByte {
+			return util.WriteInt8(w, v)
+		}
+	case int32:
+		if isVarIntType(entry.Type) {
+			return util.WriteVarInt(w, int(v))
+		}
+	case int64:
+		if entry.Type == MetaVarLong {
+			return util.WriteVarLong(w, v)
+		}
+	case float32:
+		if entry.Type == MetaFloat {
+			return util.WriteFloat32(w, v)
+		}
+	case string:
+		if entry.Type == MetaString {
+			return util.WriteString(w, v)
+		}
+	case bool:
+		if entry.Type == MetaBoolean {
+			return util.WriteBool(w, v)
+		}
+	case Particle:
+		if entry.Type == MetaParticle {
+			return writeParticle(w, v)
+		}
+	case []Particle:
+		if entry.Type == MetaParticles {
+			if err := util.WriteVarInt(w, len(v)); err != nil {
+				return err
+			}
+			for _, p := range v {
+				if err := writeParticle(w, p); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case Slot:
+		if entry.Type == MetaSlot {
+			return v.EncodeVersion(w, protocol)
+		}
+	case Position:
+		if entry.Type == MetaPosition {
+			return v.EncodeVersion(w, protocol)
+		}
+	case Rotation:
+		if entry.Type == MetaRotation {
+			return writeFloats(w, v.X, v.Y, v.Z)
+		}
+	case Vector3:
+		if entry.Type == MetaVector3 {
+			return writeFloats(w, v.X, v.Y, v.Z)
+		}
+	case Quaternion:
+		if entry.Type == MetaQuaternion {
+			return writeFloats(w, v.X, v.Y, v.Z, v.W)
+		}
+	case VillagerData:
+		if entry.Type == MetaVillagerData {
+			for _, i := range []int32{v.Type, v.Profession, v.Level} {
+				if err := util.WriteVarInt(w, int(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	return ErrInvalidMetadata
+}
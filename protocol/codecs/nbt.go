@@ -0,0 +1,52 @@
+package codecs
+
+import (
+	"io"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// NBT is the codec for NBT tags. The root tag is named before 1.20.2 and
+// nameless after, a nil V is sent as an empty (TAG_End) tag.
+type NBT struct {
+	V interface{}
+}
+
+// Decode will decode the type
+func (n NBT) Decode(r io.Reader) (interface{}, error) {
+	return n.DecodeVersion(r, version.Latest)
+}
+
+// Encode will encode the type
+func (n NBT) Encode(w io.Writer) error {
+	return n.EncodeVersion(w, version.Latest)
+}
+
+// DecodeVersion will decode the type for the protocol version
+func (n NBT) DecodeVersion(r io.Reader, protocol uint16) (interface{}, error) {
+	tag, err := readNBT(r, protocol)
+	return NBT{V: tag}, err
+}
+
+// EncodeVersion will encode the type for the protocol version
+func (n NBT) EncodeVersion(w io.Writer, protocol uint16) error {
+	return writeNBT(w, n.V, protocol)
+}
+
+func readNBT(r io.Reader, protocol uint16) (interface{}, error) {
+	if protocol >= version.V1_20_2 {
+		return nbt.ReadNetwork(r)
+	}
+
+	_, tag, err := nbt.Read(r)
+	return tag, err
+}
+
+func writeNBT(w io.Writer, tag interface{}, protocol uint16) error {
+	if protocol >= version.V1_20_2 {
+		return nbt.WriteNetwork(w, tag)
+	}
+
+	return nbt.Write(w, "", tag)
+}
@@ -0,0 +1,251 @@
+package codecs
+
+import (
+	"bytes"
+	"io"
+
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// Particle is the value of a MetaParticle entry, a particle type and its
+// options, Data holds the payload of the type as sent on the wire.
+type Particle struct {
+	Type int32
+	Data []byte
+}
+
+// ParticleTypes lists by protocol version, newest first, the number of
+// particle types and the functions reading the options of the types which
+// have any, keyed by the particle type ID. The entry used is the first whose
+// Protocol is not above the connection's.
+var ParticleTypes = []struct {
+	Protocol uint16
+	Count    int32
+	Data     map[int32]ComponentReader
+}{
+	{version.V1_21_5, 114, map[int32]ComponentReader{
+		1:   skipVarInt,    // block
+		2:   skipVarInt,    // block_marker
+		13:  skipBytes(8),  // dust
+		14:  skipBytes(12), // dust_color_transition
+		20:  skipBytes(4),  // entity_effect
+		28:  skipVarInt,    // falling_dust
+		35:  skipBytes(4),  // tinted_leaves
+		37:  skipBytes(4),  // sculk_charge
+		46:  skipItem,      // item
+		47:  skipVibration, // vibration
+		48:  skipTrail,     // trail
+		102: skipVarInt,    // shriek
+		108: skipVarInt,    // dust_pillar
+		112: skipVarInt,    // block_crumble
+	}},
+	{version.V1_21_2, 112, map[int32]ComponentReader{
+		1:   skipVarInt,    // block
+		2:   skipVarInt,    // block_marker
+		13:  skipBytes(8),  // dust
+		14:  skipBytes(12), // dust_color_transition
+		20:  skipBytes(4),  // entity_effect
+		28:  skipVarInt,    // falling_dust
+		36:  skipBytes(4),  // sculk_charge
+		45:  skipItem,      // item
+		46:  skipVibration, // vibration
+		47:  skipTrail,     // trail
+		101: skipVarInt,    // shriek
+		107: skipVarInt,    // dust_pillar
+		111: skipVarInt,    // block_crumble
+	}},
+	{version.V1_20_5, 109, map[int32]ComponentReader{
+		1:   skipVarInt,    // block
+		2:   skipVarInt,    // block_marker
+		13:  skipBytes(16), // dust
+		14:  skipBytes(28), // dust_color_transition
+		20:  skipBytes(4),  // entity_effect
+		28:  skipVarInt,    // falling_dust
+		35:  skipBytes(4),  // sculk_charge
+		44:  skipItem,      // item
+		45:  skipVibration, // vibration
+		99:  skipVarInt,    // shriek
+		105: skipVarInt,    // dust_pillar
+	}},
+	{version.V1_20_3, 101, map[int32]ComponentReader{
+		2:  skipVarInt,    // block
+		3:  skipVarInt,    // block_marker
+		14: skipBytes(16), // dust
+		15: skipBytes(28), // dust_color_transition
+		27: skipVarInt,    // falling_dust
+		33: skipBytes(4),  // sculk_charge
+		42: skipItem,      // item
+		43: skipVibration, // vibration
+		96: skipVarInt,    // shriek
+	}},
+	{version.V1_20, 95, map[int32]ComponentReader{
+		2:  skipVarInt,    // block
+		3:  skipVarInt,    // block_marker
+		14: skipBytes(16), // dust
+		15: skipBytes(28), // dust_color_transition
+		25: skipVarInt,    // falling_dust
+		31: skipBytes(4),  // sculk_charge
+		40: skipItem,      // item
+		41: skipVibration, // vibration
+		93: skipVarInt,    // shriek
+	}},
+	{version.V1_19_4, 96, map[int32]ComponentReader{
+		2:  skipVarInt,    // block
+		3:  skipVarInt,    // block_marker
+		14: skipBytes(16), // dust
+		15: skipBytes(28), // dust_color_transition
+		25: skipVarInt,    // falling_dust
+		33: skipBytes(4),  // sculk_charge
+		42: skipItem,      // item
+		43: skipVibration, // vibration
+		95: skipVarInt,    // shriek
+	}},
+	{version.V1_19, 93, map[int32]ComponentReader{
+		2:  skipVarInt,    // block
+		3:  skipVarInt,    // block_marker
+		14: skipBytes(16), // dust
+		15: skipBytes(28), // dust_color_transition
+		25: skipVarInt,    // falling_dust
+		30: skipBytes(4),  // sculk_charge
+		39: skipItem,      // item
+		40: skipVibration, // vibration
+		92: skipVarInt,    // shriek
+	}},
+	{version.V1_18, 88, map[int32]ComponentReader{
+		2:  skipVarInt,    // block
+		3:  skipVarInt,    // block_marker
+		14: skipBytes(16), // dust
+		15: skipBytes(28), // dust_color_transition
+		24: skipVarInt,    // falling_dust
+		35: skipItem,      // item
+		36: skipVibration, // vibration
+	}},
+	{version.V1_17, 89, map[int32]ComponentReader{
+		4:  skipVarInt,    // block
+		15: skipBytes(16), // dust
+		16: skipBytes(28), // dust_color_transition
+		25: skipVarInt,    // falling_dust
+		36: skipItem,      // item
+		37: skipVibration, // vibration
+	}},
+	{version.V1_16, 72, map[int32]ComponentReader{
+		3:  skipVarInt,    // block
+		14: skipBytes(16), // dust
+		23: skipVarInt,    // falling_dust
+		34: skipItem,      // item
+	}},
+	{version.V1_15, 62, map[int32]ComponentReader{
+		3:  skipVarInt,    // block
+		14: skipBytes(16), // dust
+		23: skipVarInt,    // falling_dust
+		32: skipItem,      // item
+	}},
+	{version.V1_14, 58, map[int32]ComponentReader{
+		3:  skipVarInt,    // block
+		14: skipBytes(16), // dust
+		23: skipVarInt,    // falling_dust
+		32: skipItem,      // item
+	}},
+	{version.V1_13, 50, map[int32]ComponentReader{
+		3:  skipVarInt,    // block
+		11: skipBytes(16), // dust
+		20: skipVarInt,    // falling_dust
+		27: skipItem,      // item
+	}},
+}
+
+func readParticle(r io.Reader, protocol uint16) (Particle, error) {
+	typ, err := util.ReadVarInt(r)
+	if err != nil {
+		return Particle{}, err
+	}
+
+	for _, table := range ParticleTypes {
+		if protocol < table.Protocol {
+			continue
+		}
+		if typ < 0 || int32(typ) >= table.Count {
+			return Particle{}, ErrUnknownParticle
+		}
+
+		p := Particle{Type: int32(typ)}
+		if read, ok := table.Data[p.Type]; ok {
+			var data bytes.Buffer
+			if err = read(io.TeeReader(r, &data), protocol); err != nil {
+				return Particle{}, err
+			}
+			p.Data = data.Bytes()
+		}
+
+		return p, nil
+	}
+
+	return Particle{}, ErrUnknownParticle
+}
+
+func writeParticle(w io.Writer, p Particle) error {
+	if err := util.WriteVarInt(w, int(p.Type)); err != nil {
+		return err
+	}
+
+	_, err := w.Write(p.Data)
+	return err
+}
+
+func skipItem(r io.Reader, protocol uint16) error {
+	_, err := Slot{}.DecodeVersion(r, protocol)
+	return err
+}
+
+// skipVibration will skip the options of a vibration: its origin and
+// destination before 1.19, its destination as a position source after, and
+// the ticks it travels.
+func skipVibration(r io.Reader, protocol uint16) error {
+	if protocol < version.V1_19 {
+		return skipBytes(6*8+4)(r, protocol)
+	}
+
+	var (
+		entity bool
+		err    error
+	)
+	if protocol >= version.V1_20_5 {
+		var typ int
+		typ, err = util.ReadVarInt(r)
+		entity = typ == 1
+	} else {
+		var typ string
+		typ, err = util.ReadString(r)
+		entity = typ == "minecraft:entity"
+	}
+	if err != nil {
+		return err
+	}
+
+	if entity {
+		// The entity ID and the offset of its eyes.
+		if _, err = util.ReadVarInt(r); err == nil {
+			err = skipBytes(4)(r, protocol)
+		}
+	} else {
+		err = skipBytes(8)(r, protocol)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = util.ReadVarInt(r)
+	return err
+}
+
+// skipTrail will skip the target and color of a trail, and its duration
+// since 1.21.4.
+func skipTrail(r io.Reader, protocol uint16) error {
+	if err := skipBytes(3*8+4)(r, protocol); err != nil || protocol < version.V1_21_4 {
+		return err
+	}
+
+	_, err := util.ReadVarInt(r)
+	return err
+}
@@ -0,0 +1,54 @@
+package codecs
+
+import (
+	"io"
+
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// Position is the codec for block positions, packed into a long. The order of
+// the Y and Z components changed in 1.14.
+type Position struct {
+	X, Y, Z int32
+}
+
+// Decode will decode the type
+func (p Position) Decode(r io.Reader) (interface{}, error) {
+	return p.DecodeVersion(r, version.Latest)
+}
+
+// Encode will encode the type
+func (p Position) Encode(w io.Writer) error {
+	return p.EncodeVersion(w, version.Latest)
+}
+
+// DecodeVersion will decode the type for the protocol version
+func (p Position) DecodeVersion(r io.Reader, protocol uint16) (interface{}, error) {
+	val, err := util.ReadInt64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p.X = int32(val >> 38)
+	if protocol >= version.V1_14 {
+		p.Y = int32(val << 52 >> 52)
+		p.Z = int32(val << 26 >> 38)
+	} else {
+		p.Y = int32(val << 26 >> 52)
+		p.Z = int32(val << 38 >> 38)
+	}
+
+	return p, nil
+}
+
+// EncodeVersion will encode the type for the protocol version
+func (p Position) EncodeVersion(w io.Writer, protocol uint16) error {
+	x, y, z := int64(p.X)&0x3FFFFFF, int64(p.Y)&0xFFF, int64(p.Z)&0x3FFFFFF
+
+	if protocol >= version.V1_14 {
+		return util.WriteInt64(w, x<<38|z<<12|y)
+	}
+
+	return util.WriteInt64(w, x<<38|y<<26|z)
+}
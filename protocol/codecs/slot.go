@@ -0,0 +1,635 @@
+package codecs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// Possible Errors.
+var (
+	// ErrUnknownItemComponent is returned when a slot holds an item component
+	// without a reader, since its length cannot be known.
+	ErrUnknownItemComponent = errors.New("unknown item component type")
+	// ErrNoItemComponents is returned when a slot holds item components of a
+	// protocol version without a table of item component readers.
+	ErrNoItemComponents = errors.New("no item component readers for the protocol version")
+)
+
+// ItemStack is a stack of items, a stack with a Count of zero is an empty slot.
+type ItemStack struct {
+	ID    int32
+	Count int32
+
+	// Damage is the damage or metadata value of the item, used before 1.13.
+	Damage int16
+
+	// NBT is the tag of the item, used before 1.20.5.
+	NBT nbt.Compound
+
+	// Components are the components added to or overriding the item's
+	// defaults, and RemovedComponents the types removed from them, both are
+	// used since 1.20.5.
+	Components        []ItemComponent
+	RemovedComponents []int32
+}
+
+// ItemComponent is a single data component of an item, Data holds its payload as sent on the wire.
+type ItemComponent struct {
+	Type int32
+	Data []byte
+}
+
+// Empty reports whether the stack represents an empty slot.
+func (s ItemStack) Empty() bool {
+	return s.Count <= 0
+}
+
+// ComponentReader consumes the payload of a single item component from the reader.
+type ComponentReader func(r io.Reader, protocol uint16) error
+
+// itemComponents holds, for every protocol version with components, the
+// functions reading the payload of each item component type, keyed by its
+// ID in the component registry of the version. Component IDs change between
+// versions, and components of involved layouts are left out, so slots
+// holding those cannot be read until a table covering them is set with
+// SetItemComponentReaders.
+var (
+	itemComponents = map[uint16]map[int32]ComponentReader{
+		version.V1_20_5: {
+			0:  skipNBT,      // custom_data
+			1:  skipVarInt,   // max_stack_size
+			2:  skipVarInt,   // max_damage
+			3:  skipVarInt,   // damage
+			4:  skipBytes(1), // unbreakable
+			5:  skipNBT,      // custom_name
+			6:  skipNBT,      // item_name
+			7:  skipNBTList,  // lore
+			8:  skipVarInt,   // rarity
+			9:  skipEnchants, // enchantments
+			13: skipVarInt,   // custom_model_data
+			14: skipBytes(0), // hide_additional_tooltip
+			15: skipBytes(0), // hide_tooltip
+			16: skipVarInt,   // repair_cost
+			17: skipBytes(0), // creative_slot_lock
+			18: skipBytes(1), // enchantment_glint_override
+			19: skipNBT,      // intangible_projectile
+			21: skipBytes(0), // fire_resistant
+			23: skipEnchants, // stored_enchantments
+			24: skipBytes(5), // dyed_color
+			25: skipBytes(4), // map_color
+			26: skipVarInt,   // map_id
+			27: skipNBT,      // map_decorations
+			28: skipVarInt,   // map_post_processing
+			36: skipNBT,      // debug_stick_state
+			37: skipNBT,      // entity_data
+			38: skipNBT,      // bucket_entity_data
+			39: skipNBT,      // block_entity_data
+			41: skipVarInt,   // ominous_bottle_amplifier
+			42: skipNBT,      // recipes
+			47: skipString,   // note_block_sound
+			49: skipVarInt,   // base_color
+			54: skipNBT,      // lock
+			55: skipNBT,      // container_loot
+		},
+		version.V1_21: {
+			0:  skipNBT,      // custom_data
+			1:  skipVarInt,   // max_stack_size
+			2:  skipVarInt,   // max_damage
+			3:  skipVarInt,   // damage
+			4:  skipBytes(1), // unbreakable
+			5:  skipNBT,      // custom_name
+			6:  skipNBT,      // item_name
+			7:  skipNBTList,  // lore
+			8:  skipVarInt,   // rarity
+			9:  skipEnchants, // enchantments
+			13: skipVarInt,   // custom_model_data
+			14: skipBytes(0), // hide_additional_tooltip
+			15: skipBytes(0), // hide_tooltip
+			16: skipVarInt,   // repair_cost
+			17: skipBytes(0), // creative_slot_lock
+			18: skipBytes(1), // enchantment_glint_override
+			19: skipNBT,      // intangible_projectile
+			21: skipBytes(0), // fire_resistant
+			23: skipEnchants, // stored_enchantments
+			24: skipBytes(5), // dyed_color
+			25: skipBytes(4), // map_color
+			26: skipVarInt,   // map_id
+			27: skipNBT,      // map_decorations
+			28: skipVarInt,   // map_post_processing
+			36: skipNBT,      // debug_stick_state
+			37: skipNBT,      // entity_data
+			38: skipNBT,      // bucket_entity_data
+			39: skipNBT,      // block_entity_data
+			41: skipVarInt,   // ominous_bottle_amplifier
+			43: skipNBT,      // recipes
+			48: skipString,   // note_block_sound
+			50: skipVarInt,   // base_color
+			55: skipNBT,      // lock
+			56: skipNBT,      // container_loot
+		},
+		version.V1_21_2: {
+			0:  skipNBT,      // custom_data
+			1:  skipVarInt,   // max_stack_size
+			2:  skipVarInt,   // max_damage
+			3:  skipVarInt,   // damage
+			4:  skipBytes(1), // unbreakable
+			5:  skipNBT,      // custom_name
+			6:  skipNBT,      // item_name
+			7:  skipString,   // item_model
+			8:  skipNBTList,  // lore
+			9:  skipVarInt,   // rarity
+			10: skipEnchants, // enchantments
+			14: skipVarInt,   // custom_model_data
+			15: skipBytes(0), // hide_additional_tooltip
+			16: skipBytes(0), // hide_tooltip
+			17: skipVarInt,   // repair_cost
+			18: skipBytes(0), // creative_slot_lock
+			19: skipBytes(1), // enchantment_glint_override
+			20: skipNBT,      // intangible_projectile
+			25: skipString,   // damage_resistant
+			27: skipVarInt,   // enchantable
+			30: skipBytes(0), // glider
+			31: skipString,   // tooltip_style
+			33: skipEnchants, // stored_enchantments
+			34: skipBytes(5), // dyed_color
+			35: skipBytes(4), // map_color
+			36: skipVarInt,   // map_id
+			37: skipNBT,      // map_decorations
+			38: skipVarInt,   // map_post_processing
+			46: skipNBT,      // debug_stick_state
+			47: skipNBT,      // entity_data
+			48: skipNBT,      // bucket_entity_data
+			49: skipNBT,      // block_entity_data
+			51: skipVarInt,   // ominous_bottle_amplifier
+			53: skipNBT,      // recipes
+			58: skipString,   // note_block_sound
+			60: skipVarInt,   // base_color
+			65: skipNBT,      // lock
+			66: skipNBT,      // container_loot
+		},
+		version.V1_21_5: {
+			0:  skipNBT,             // custom_data
+			1:  skipVarInt,          // max_stack_size
+			2:  skipVarInt,          // max_damage
+			3:  skipVarInt,          // damage
+			4:  skipBytes(0),        // unbreakable
+			5:  skipNBT,             // custom_name
+			6:  skipNBT,             // item_name
+			7:  skipString,          // item_model
+			8:  skipNBTList,         // lore
+			9:  skipVarInt,          // rarity
+			10: skipEnchants,        // enchantments
+			14: skipCustomModelData, // custom_model_data
+			15: skipTooltipDisplay,  // tooltip_display
+			16: skipVarInt,          // repair_cost
+			17: skipBytes(0),        // creative_slot_lock
+			18: skipBytes(1),        // enchantment_glint_override
+			19: skipNBT,             // intangible_projectile
+			24: skipString,          // damage_resistant
+			27: skipVarInt,          // enchantable
+			30: skipBytes(0),        // glider
+			31: skipString,          // tooltip_style
+			34: skipEnchants,        // stored_enchantments
+			35: skipBytes(4),        // dyed_color
+			36: skipBytes(4),        // map_color
+			37: skipVarInt,          // map_id
+			38: skipNBT,             // map_decorations
+			39: skipVarInt,          // map_post_processing
+			43: skipBytes(4),        // potion_duration_scale
+			48: skipNBT,             // debug_stick_state
+			49: skipNBT,             // entity_data
+			50: skipNBT,             // bucket_entity_data
+			51: skipNBT,             // block_entity_data
+			54: skipVarInt,          // ominous_bottle_amplifier
+			57: skipNBT,             // recipes
+			62: skipString,          // note_block_sound
+			64: skipVarInt,          // base_color
+			69: skipNBT,             // lock
+			70: skipNBT,             // container_loot
+			72: skipVarInt,          // villager/variant
+			73: skipVarInt,          // wolf/variant
+			74: skipVarInt,          // wolf/sound_variant
+			75: skipVarInt,          // wolf/collar
+			76: skipVarInt,          // fox/variant
+			77: skipVarInt,          // salmon/size
+			78: skipVarInt,          // parrot/variant
+			79: skipVarInt,          // tropical_fish/pattern
+			80: skipVarInt,          // tropical_fish/base_color
+			81: skipVarInt,          // tropical_fish/pattern_color
+			82: skipVarInt,          // mooshroom/variant
+			83: skipVarInt,          // rabbit/variant
+			84: skipVarInt,          // pig/variant
+			85: skipVarInt,          // cow/variant
+			87: skipVarInt,          // frog/variant
+			88: skipVarInt,          // horse/variant
+			90: skipVarInt,          // llama/variant
+			91: skipVarInt,          // axolotl/variant
+			92: skipVarInt,          // cat/variant
+			93: skipVarInt,          // cat/collar
+			94: skipVarInt,          // sheep/color
+			95: skipVarInt,          // shulker/color
+		},
+	}
+	itemComponentsLock sync.RWMutex
+)
+
+func init() {
+	// 1.21.4 only changed the layout of custom_model_data.
+	components := make(map[int32]ComponentReader, len(itemComponents[version.V1_21_2]))
+	for typ, read := range itemComponents[version.V1_21_2] {
+		components[typ] = read
+	}
+	components[14] = skipCustomModelData
+	itemComponents[version.V1_21_4] = components
+
+	// Components holding further slots refer back to the table, so they are
+	// added here to avoid an initialization cycle.
+	for protocol, slots := range map[uint16]map[int32]ComponentReader{
+		version.V1_20_5: {29: skipSlotList, 30: skipSlotList, 51: skipSlotList},
+		version.V1_21:   {29: skipSlotList, 30: skipSlotList, 52: skipSlotList},
+		version.V1_21_2: {23: skipSlot, 39: skipSlotList, 40: skipSlotList, 62: skipSlotList},
+		version.V1_21_4: {23: skipSlot, 39: skipSlotList, 40: skipSlotList, 62: skipSlotList},
+		version.V1_21_5: {22: skipSlot, 40: skipSlotList, 41: skipSlotList, 66: skipSlotList},
+	} {
+		// use_remainder, charged_projectiles, bundle_contents and container.
+		for typ, read := range slots {
+			itemComponents[protocol][typ] = read
+		}
+	}
+}
+
+// ItemComponentReaders will return a copy of the item component readers of
+// the protocol version, and whether it has any.
+func ItemComponentReaders(protocol uint16) (map[int32]ComponentReader, bool) {
+	itemComponentsLock.RLock()
+	defer itemComponentsLock.RUnlock()
+
+	readers, ok := itemComponents[protocol]
+	if !ok {
+		return nil, false
+	}
+
+	out := make(map[int32]ComponentReader, len(readers))
+	for typ, read := range readers {
+		out[typ] = read
+	}
+	return out, true
+}
+
+// SetItemComponentReaders will set the item component readers of the protocol
+// version, keyed by the IDs of its component registry, replacing those it had.
+// The map is copied.
+func SetItemComponentReaders(protocol uint16, readers map[int32]ComponentReader) {
+	table := make(map[int32]ComponentReader, len(readers))
+	for typ, read := range readers {
+		table[typ] = read
+	}
+
+	itemComponentsLock.Lock()
+	defer itemComponentsLock.Unlock()
+	itemComponents[protocol] = table
+}
+
+// itemComponentReader will return the reader of the item component type of
+// the protocol version.
+func itemComponentReader(protocol uint16, typ int32) (ComponentReader, error) {
+	itemComponentsLock.RLock()
+	defer itemComponentsLock.RUnlock()
+
+	readers, ok := itemComponents[protocol]
+	if !ok {
+		return nil, ErrNoItemComponents
+	}
+	read, ok := readers[typ]
+	if !ok {
+		return nil, ErrUnknownItemComponent
+	}
+
+	return read, nil
+}
+
+// Slot is the codec for inventory slots.
+type Slot ItemStack
+
+// Decode will decode the type
+func (s Slot) Decode(r io.Reader) (interface{}, error) {
+	return s.DecodeVersion(r, version.Latest)
+}
+
+// Encode will encode the type
+func (s Slot) Encode(w io.Writer) error {
+	return s.EncodeVersion(w, version.Latest)
+}
+
+// DecodeVersion will decode the type for the protocol version
+func (s Slot) DecodeVersion(r io.Reader, protocol uint16) (interface{}, error) {
+	var (
+		stack ItemStack
+		err   error
+	)
+
+	switch {
+	case protocol >= version.V1_20_5:
+		stack, err = readComponentSlot(r, protocol)
+	case protocol >= version.V1_13_2:
+		stack, err = readFlaggedSlot(r, protocol)
+	default:
+		stack, err = readLegacySlot(r, protocol)
+	}
+
+	return Slot(stack), err
+}
+
+// EncodeVersion will encode the type for the protocol version
+func (s Slot) EncodeVersion(w io.Writer, protocol uint16) error {
+	switch {
+	case protocol >= version.V1_20_5:
+		return writeComponentSlot(w, ItemStack(s), protocol)
+	case protocol >= version.V1_13_2:
+		return writeFlaggedSlot(w, ItemStack(s), protocol)
+	}
+
+	return writeLegacySlot(w, ItemStack(s), protocol)
+}
+
+func readLegacySlot(r io.Reader, protocol uint16) (stack ItemStack, err error) {
+	id, err := util.ReadInt16(r)
+	if err != nil || id == -1 {
+		return
+	}
+	stack.ID = int32(id)
+
+	count, err := util.ReadInt8(r)
+	if err != nil {
+		return
+	}
+	stack.Count = int32(count)
+
+	if protocol < version.V1_13 {
+		if stack.Damage, err = util.ReadInt16(r); err != nil {
+			return
+		}
+	}
+
+	stack.NBT, err = readItemTag(r, protocol)
+	return
+}
+
+func writeLegacySlot(w io.Writer, stack ItemStack, protocol uint16) error {
+	if stack.Empty() {
+		return util.WriteInt16(w, -1)
+	}
+
+	if err := util.WriteInt16(w, int16(stack.ID)); err != nil {
+		return err
+	}
+	if err := util.WriteInt8(w, int8(stack.Count)); err != nil {
+		return err
+	}
+	if protocol < version.V1_13 {
+		if err := util.WriteInt16(w, stack.Damage); err != nil {
+			return err
+		}
+	}
+
+	return writeItemTag(w, stack.NBT, protocol)
+}
+
+func readFlaggedSlot(r io.Reader, protocol uint16) (stack ItemStack, err error) {
+	present, err := util.ReadBool(r)
+	if err != nil || !present {
+		return
+	}
+
+	id, err := util.ReadVarInt(r)
+	if err != nil {
+		return
+	}
+	stack.ID = int32(id)
+
+	count, err := util.ReadInt8(r)
+	if err != nil {
+		return
+	}
+	stack.Count = int32(count)
+
+	stack.NBT, err = readItemTag(r, protocol)
+	return
+}
+
+func writeFlaggedSlot(w io.Writer, stack ItemStack, protocol uint16) error {
+	if err := util.WriteBool(w, !stack.Empty()); err != nil || stack.Empty() {
+		return err
+	}
+
+	if err := util.WriteVarInt(w, int(stack.ID)); err != nil {
+		return err
+	}
+	if err := util.WriteInt8(w, int8(stack.Count)); err != nil {
+		return err
+	}
+
+	return writeItemTag(w, stack.NBT, protocol)
+}
+
+func readComponentSlot(r io.Reader, protocol uint16) (stack ItemStack, err error) {
+	count, err := util.ReadVarInt(r)
+	if err != nil || count <= 0 {
+		return
+	}
+	stack.Count = int32(count)
+
+	id, err := util.ReadVarInt(r)
+	if err != nil {
+		return
+	}
+	stack.ID = int32(id)
+
+	added, err := util.ReadVarInt(r)
+	if err != nil {
+		return
+	}
+	removed, err := util.ReadVarInt(r)
+	if err != nil {
+		return
+	}
+	if added < 0 || removed < 0 {
+		err = ErrUnknownItemComponent
+		return
+	}
+
+	for i := 0; i < added; i++ {
+		typ, err := util.ReadVarInt(r)
+		if err != nil {
+			return stack, err
+		}
+
+		read, err := itemComponentReader(protocol, int32(typ))
+		if err != nil {
+			return stack, err
+		}
+
+		var data bytes.Buffer
+		if err = read(io.TeeReader(r, &data), protocol); err != nil {
+			return stack, err
+		}
+
+		stack.Components = append(stack.Components, ItemComponent{Type: int32(typ), Data: data.Bytes()})
+	}
+
+	for i := 0; i < removed; i++ {
+		typ, err := util.ReadVarInt(r)
+		if err != nil {
+			return stack, err
+		}
+
+		stack.RemovedComponents = append(stack.RemovedComponents, int32(typ))
+	}
+
+	return
+}
+
+func writeComponentSlot(w io.Writer, stack ItemStack, protocol uint16) error {
+	if stack.Empty() {
+		return util.WriteVarInt(w, 0)
+	}
+
+	for _, v := range []int{int(stack.Count), int(stack.ID), len(stack.Components), len(stack.RemovedComponents)} {
+		if err := util.WriteVarInt(w, v); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range stack.Components {
+		if err := util.WriteVarInt(w, int(c.Type)); err != nil {
+			return err
+		}
+		if _, err := w.Write(c.Data); err != nil {
+			return err
+		}
+	}
+
+	for _, typ := range stack.RemovedComponents {
+		if err := util.WriteVarInt(w, int(typ)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readItemTag(r io.Reader, protocol uint16) (nbt.Compound, error) {
+	tag, err := readNBT(r, protocol)
+	if err != nil || tag == nil {
+		return nil, err
+	}
+
+	c, ok := tag.(nbt.Compound)
+	if !ok {
+		return nil, nbt.ErrInvalidTagType
+	}
+
+	return c, nil
+}
+
+func writeItemTag(w io.Writer, tag nbt.Compound, protocol uint16) error {
+	// A nil compound has to be passed on as an untyped nil to be sent as TAG_End.
+	if tag == nil {
+		return writeNBT(w, nil, protocol)
+	}
+
+	return writeNBT(w, tag, protocol)
+}
+
+func skipBytes(n int) ComponentReader {
+	return func(r io.Reader, protocol uint16) error {
+		_, err := io.CopyN(io.Discard, r, int64(n))
+		return err
+	}
+}
+
+func skipVarInt(r io.Reader, protocol uint16) error {
+	_, err := util.ReadVarInt(r)
+	return err
+}
+
+func skipString(r io.Reader, protocol uint16) error {
+	_, err := util.ReadString(r)
+	return err
+}
+
+func skipNBT(r io.Reader, protocol uint16) error {
+	_, err := readNBT(r, protocol)
+	return err
+}
+
+func skipNBTList(r io.Reader, protocol uint16) error {
+	n, err := util.ReadVarInt(r)
+	for i := 0; i < n && err == nil; i++ {
+		err = skipNBT(r, protocol)
+	}
+	return err
+}
+
+func skipSlot(r io.Reader, protocol uint16) error {
+	_, err := readComponentSlot(r, protocol)
+	return err
+}
+
+func skipSlotList(r io.Reader, protocol uint16) error {
+	n, err := util.ReadVarInt(r)
+	for i := 0; i < n && err == nil; i++ {
+		_, err = readComponentSlot(r, protocol)
+	}
+	return err
+}
+
+func skipEnchants(r io.Reader, protocol uint16) error {
+	n, err := util.ReadVarInt(r)
+	for i := 0; i < 2*n && err == nil; i++ {
+		_, err = util.ReadVarInt(r)
+	}
+	if err != nil || protocol >= version.V1_21_5 {
+		return err
+	}
+
+	// The flag showing them in the tooltip moved to tooltip_display in 1.21.5.
+	_, err = util.ReadBool(r)
+	return err
+}
+
+// skipCustomModelData will skip the lists of floats, flags, strings and
+// colors custom_model_data holds since 1.21.4.
+func skipCustomModelData(r io.Reader, protocol uint16) error {
+	for _, skip := range []ComponentReader{skipBytes(4), skipBytes(1), skipString, skipBytes(4)} {
+		n, err := util.ReadVarInt(r)
+		for i := 0; i < n && err == nil; i++ {
+			err = skip(r, protocol)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipTooltipDisplay will skip the hide_tooltip flag and the list of hidden
+// component types of tooltip_display.
+func skipTooltipDisplay(r io.Reader, protocol uint16) error {
+	if _, err := util.ReadBool(r); err != nil {
+		return err
+	}
+
+	n, err := util.ReadVarInt(r)
+	for i := 0; i < n && err == nil; i++ {
+		_, err = util.ReadVarInt(r)
+	}
+	return err
+}
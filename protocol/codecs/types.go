@@ -7,18 +7,33 @@ import (
 	"justanother.org/protocolhelper/util"
 )
 
-// String is the codec for strings
+// MaxJSONLength is the maximum length of JSON strings, such as chat
+// components, in UTF-16 code units.
+const MaxJSONLength = 262144
+
+// String is the codec for strings. They hold at most util.MaxStringLength
+// UTF-16 code units unless the field declares another maximum.
 type String string
 
 // Decode will decode the type
 func (s String) Decode(r io.Reader) (interface{}, error) {
-	str, err := util.ReadString(r)
-	return String(str), err
+	return s.DecodeMax(r, util.MaxStringLength)
 }
 
 // Encode will encode the type
 func (s String) Encode(w io.Writer) error {
-	return util.WriteString(w, string(s))
+	return s.EncodeMax(w, util.MaxStringLength)
+}
+
+// DecodeMax will decode the type, holding at most max UTF-16 code units
+func (s String) DecodeMax(r io.Reader, max int) (interface{}, error) {
+	str, err := util.ReadStringMax(r, max)
+	return String(str), err
+}
+
+// EncodeMax will encode the type, holding at most max UTF-16 code units
+func (s String) EncodeMax(w io.Writer, max int) error {
+	return util.WriteStringMax(w, string(s), max)
 }
 
 // JSON is the codec for JSON encoded objects (technically strings)
@@ -28,7 +43,7 @@ type JSON struct {
 
 // Decode will decode the type
 func (j JSON) Decode(r io.Reader) (interface{}, error) {
-	s, err := util.ReadString(r)
+	s, err := util.ReadStringMax(r, MaxJSONLength)
 	if err != nil {
 		return nil, err
 	}
@@ -46,8 +61,7 @@ func (j JSON) Encode(w io.Writer) error {
 		return err
 	}
 
-	str := String(string(data))
-	return str.Encode(w)
+	return util.WriteStringMax(w, string(data), MaxJSONLength)
 }
 
 // VarInt is the codec for ints
@@ -106,17 +120,34 @@ func (b UnsignedByte) Encode(w io.Writer) error {
 	return util.WriteUint8(w, uint8(b))
 }
 
-// ByteArray is the codec for arrays of bytes
+// ByteArray is the codec for arrays of bytes. Unless the field declares a
+// maximum, they are only limited by the size of the packet.
 type ByteArray []byte
 
+// maxByteArrayLength is the size of the largest packet.
+const maxByteArrayLength = 2097151
+
 // Decode will decode the type
 func (b ByteArray) Decode(r io.Reader) (interface{}, error) {
+	return b.DecodeMax(r, maxByteArrayLength)
+}
+
+// Encode will encode the type
+func (b ByteArray) Encode(w io.Writer) error {
+	return b.EncodeMax(w, maxByteArrayLength)
+}
+
+// DecodeMax will decode the type, holding at most max bytes
+func (b ByteArray) DecodeMax(r io.Reader, max int) (interface{}, error) {
 	l, err := util.ReadVarInt(r)
 	if err != nil {
 		return nil, err
 	}
+	if l < 0 || l > max {
+		return nil, ErrInvalidLength
+	}
 
-	buf := make([]byte, int(l))
+	buf := make([]byte, l)
 	_, err = io.ReadFull(r, buf)
 	if err != nil {
 		return nil, err
@@ -125,8 +156,12 @@ func (b ByteArray) Decode(r io.Reader) (interface{}, error) {
 	return buf, nil
 }
 
-// Encode will encode the type
-func (b ByteArray) Encode(w io.Writer) error {
+// EncodeMax will encode the type, holding at most max bytes
+func (b ByteArray) EncodeMax(w io.Writer, max int) error {
+	if len(b) > max {
+		return ErrInvalidLength
+	}
+
 	err := util.WriteVarInt(w, len(b))
 	if err != nil {
 		return err
@@ -226,7 +261,7 @@ type Float float32
 
 // Decode will decode the type
 func (f Float) Decode(r io.Reader) (interface{}, error) {
-	ft, err := util.ReadInt64(r)
+	ft, err := util.ReadFloat32(r)
 	return Float(ft), err
 }
 
@@ -248,3 +283,48 @@ func (d Double) Decode(r io.Reader) (interface{}, error) {
 func (d Double) Encode(w io.Writer) error {
 	return util.WriteFloat64(w, float64(d))
 }
+
+// UUID is the codec for UUIDs, sent as two big endian longs
+type UUID [16]byte
+
+// Decode will decode the type
+func (u UUID) Decode(r io.Reader) (interface{}, error) {
+	_, err := io.ReadFull(r, u[:])
+	return u, err
+}
+
+// Encode will encode the type
+func (u UUID) Encode(w io.Writer) error {
+	_, err := w.Write(u[:])
+	return err
+}
+
+// Signature is the codec for chat message signatures, 256 bytes of RSA signature
+type Signature [256]byte
+
+// Decode will decode the type
+func (s Signature) Decode(r io.Reader) (interface{}, error) {
+	_, err := io.ReadFull(r, s[:])
+	return s, err
+}
+
+// Encode will encode the type
+func (s Signature) Encode(w io.Writer) error {
+	_, err := w.Write(s[:])
+	return err
+}
+
+// RawData is the codec for the rest of a packet, such as the payload of a
+// plugin message, sent without a length
+type RawData []byte
+
+// Decode will decode the type
+func (d RawData) Decode(r io.Reader) (interface{}, error) {
+	return io.ReadAll(r)
+}
+
+// Encode will encode the type
+func (d RawData) Encode(w io.Writer) error {
+	_, err := w.Write(d)
+	return err
+}
@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"reflect"
+
+	"justanother.org/protocolhelper/protocol/packet"
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// ConfigurationPhase describes what a server sends to a client in the
+// configuration state.
+type ConfigurationPhase struct {
+	// KnownPacks are offered to the client since 1.20.5. The packs the client
+	// knows as well are passed to Packets, so registry entries found in them
+	// can be sent without their data.
+	KnownPacks []packet.KnownPack
+
+	// Packets will return the packets to send, such as registry data,
+	// feature flags and tags.
+	Packets func(known []packet.KnownPack) []packet.Holder
+
+	// Handle is called with every other packet the client sends during the
+	// phase, such as its client information, plugin messages or resource pack
+	// responses. An error ends the phase.
+	Handle func(h packet.Holder) error
+}
+
+// Configure will drive the configuration phase of a server side connection,
+// leaving it in the Play state. It is called in the Login state once
+// LoginSuccess has been sent, or in the Play state to reconfigure the client.
+// The configuration packets have to be registered, see RegisterConfiguration.
+func (c *Connection) Configure(phase ConfigurationPhase) error {
+	if c.Protocol < version.V1_20_2 {
+		return ErrNoConfiguration
+	}
+
+	switch c.State {
+	case Login:
+		if _, err := c.await(phase.Handle, isPacket(packet.LoginAcknowledged{})); err != nil {
+			return err
+		}
+	case Play:
+		if _, err := c.Write(packet.PlayStartConfiguration{}); err != nil {
+			return err
+		}
+		if _, err := c.await(phase.Handle, isPacket(packet.PlayAcknowledgeConfiguration{})); err != nil {
+			return err
+		}
+	case Configuration:
+	default:
+		return ErrInvalidState
+	}
+	c.State = Configuration
+
+	var known []packet.KnownPack
+	if phase.KnownPacks != nil && c.Protocol >= version.V1_20_5 {
+		if _, err := c.Write(packet.ConfigClientboundKnownPacks{Packs: phase.KnownPacks}); err != nil {
+			return err
+		}
+
+		h, err := c.await(phase.Handle, isPacket(packet.ConfigServerboundKnownPacks{}))
+		if err != nil {
+			return err
+		}
+		known = h.(packet.ConfigServerboundKnownPacks).Packs
+	}
+
+	if phase.Packets != nil {
+		for _, h := range phase.Packets(known) {
+			if _, err := c.Write(h); err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err := c.Write(packet.ConfigFinish{}); err != nil {
+		return err
+	}
+	if _, err := c.await(phase.Handle, isPacket(packet.ConfigAcknowledgeFinish{})); err != nil {
+		return err
+	}
+
+	c.State = Play
+	return nil
+}
+
+// await will read packets until one matches, passing the others to handle.
+// Packets which are not registered are skipped.
+func (c *Connection) await(handle func(packet.Holder) error, match func(packet.Holder) bool) (packet.Holder, error) {
+	for {
+		h, err := c.Next()
+		if err == ErrUnknownPacketType {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if match(h) {
+			return h, nil
+		}
+		if handle != nil {
+			if err = handle(h); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// isPacket will return a matcher for packets of the same type as want.
+func isPacket(want packet.Holder) func(packet.Holder) bool {
+	return func(h packet.Holder) bool {
+		return reflect.TypeOf(h) == reflect.TypeOf(want)
+	}
+}
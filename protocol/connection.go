@@ -1,12 +1,10 @@
 package protocol
 
 import (
-	"bytes"
 	"io"
 	"net"
 	"reflect"
 
-	"justanother.org/protocolhelper/protocol/codecs"
 	"justanother.org/protocolhelper/protocol/packet"
 	"justanother.org/protocolhelper/util"
 )
@@ -17,6 +15,10 @@ type Connection struct {
 
 	State    State
 	Protocol uint16
+
+	// Direction is the direction of the packets read from the connection,
+	// Serverbound for servers and Clientbound for clients.
+	Direction Direction
 }
 
 // State is the gameplay state.
@@ -28,6 +30,8 @@ const (
 	Status
 	Login
 	Play
+	// Configuration sits between Login and Play since 1.20.2.
+	Configuration
 )
 
 // NewConnection will wrap the net.Conn in a Connection struct
@@ -41,6 +45,7 @@ func (c *Connection) Next() (packet.Holder, error) {
 	if err != nil {
 		return nil, err
 	}
+	defer util.ReleaseBuffer(p.Data)
 
 	return c.decode(p)
 }
@@ -51,18 +56,24 @@ func (c *Connection) Write(h packet.Holder) (int, error) {
 	if err != nil {
 		return -1, err
 	}
-
-	err = util.WriteVarInt(c.rw, data.Len())
-	if err != nil {
-		return -1, err
+	defer util.ReleaseBuffer(data)
+	if data.Len() > MaxPacketLength {
+		return -1, ErrInvalidPacketLength
 	}
 
-	n, err := data.WriteTo(c.rw)
-	if err != nil {
-		return int(n), err
+	// The length prefix and the packet are sent with a single write.
+	frame := util.AcquireBuffer()
+	defer util.ReleaseBuffer(frame)
+	frame.PutVarInt(data.Len())
+	prefix := frame.Len()
+	frame.PutBytes(data.Bytes())
+
+	n, err := c.rw.Write(frame.Bytes())
+	if n -= prefix; n < 0 {
+		n = 0
 	}
 
-	return int(n), nil
+	return n, err
 }
 
 // Close will attempt to close the connection
@@ -80,79 +91,57 @@ func (c *Connection) read() (*Packet, error) {
 		return nil, err
 	}
 
-	if length < 0 || length > 1048576 { // 2^(21-1)
+	if length < 0 || length > MaxPacketLength {
 		return nil, ErrInvalidPacketLength
 	}
 
-	payload := make([]byte, length)
-	_, err = io.ReadFull(c.rw, payload)
-
-	if err != nil {
+	buffer := util.AcquireBuffer()
+	if _, err = io.ReadFull(c.rw, buffer.Grow(length)); err != nil {
+		util.ReleaseBuffer(buffer)
 		return nil, err
 	}
 
-	buffer := bytes.NewBuffer(payload)
 	id, err := util.ReadVarInt(buffer)
-
 	if err != nil {
+		util.ReleaseBuffer(buffer)
 		return nil, err
 	}
 
 	return &Packet{
 		ID:        id,
-		Direction: Serverbound,
-		Data:      *buffer,
+		Direction: c.Direction,
+		Data:      buffer,
 	}, nil
 }
 
 func (c *Connection) decode(p *Packet) (packet.Holder, error) {
-	packetType, err := GetPacketType(p.Direction, c.State, p.ID)
+	packetType, err := getPacketTypeVersion(p.Direction, c.State, p.ID, c.Protocol)
 	if err != nil {
 		return nil, err
 	}
 
 	inst := reflect.New(packetType).Elem()
-
-	for i := 0; i < inst.NumField(); i++ {
-		field := inst.Field(i)
-
-		codec, ok := field.Interface().(codecs.Codec)
-		if !ok {
-			return nil, codecs.UnknownCodecType
-		}
-
-		value, err := codec.Decode(&p.Data)
-		if err != nil {
-			return nil, err
-		}
-
-		field.Set(reflect.ValueOf(value))
+	if err = decodeStruct(p.Data, inst, c.Protocol); err != nil {
+		return nil, err
 	}
 
 	return inst.Interface().(packet.Holder), nil
 }
 
-func (c *Connection) encode(h packet.Holder) (*bytes.Buffer, error) {
-	buffer := new(bytes.Buffer)
-	util.WriteVarInt(buffer, h.ID())
-
-	value := reflect.ValueOf(h)
-
-	for i := 0; i < value.NumField(); i++ {
-		field := value.Field(i)
-
-		codec, ok := field.Interface().(codecs.Codec)
-		if !ok {
-			if field.Kind() == reflect.Struct {
-				codec = codecs.JSON{V: field.Interface()}
-			} else {
-				return nil, codecs.UnknownCodecType
-			}
+func (c *Connection) encode(h packet.Holder) (*util.Buffer, error) {
+	id := h.ID()
+	if v, ok := h.(packet.VersionedHolder); ok {
+		if id = v.IDVersion(c.Protocol); id < 0 {
+			return nil, ErrUnsupportedProtocol
 		}
+	}
 
-		if err := codec.Encode(buffer); err != nil {
-			return nil, err
-		}
+	buffer := util.AcquireBuffer()
+	buffer.PutVarInt(id)
+
+	if err := encodeStruct(buffer, reflect.ValueOf(h), c.Protocol); err != nil {
+		util.ReleaseBuffer(buffer)
+		return nil, err
 	}
 
 	return buffer, nil
@@ -0,0 +1,135 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"justanother.org/protocolhelper/chat"
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/packet"
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// loopback is a connection reading from frames written before, over and
+// over, and discarding what is written to it.
+type loopback struct {
+	bytes.Reader
+	frames []byte
+}
+
+func (l *loopback) Read(p []byte) (int, error) {
+	if l.Len() == 0 {
+		l.Reset(l.frames)
+	}
+
+	return l.Reader.Read(p)
+}
+
+func (l *loopback) ReadByte() (byte, error) {
+	if l.Len() == 0 {
+		l.Reset(l.frames)
+	}
+
+	return l.Reader.ReadByte()
+}
+
+func (l *loopback) Write(p []byte) (int, error) { return len(p), nil }
+
+func (l *loopback) Close() error { return nil }
+
+var benchmarkPackets = []struct {
+	name   string
+	state  State
+	packet packet.Holder
+}{
+	{"Handshake", Handshake, packet.Handshake{ProtocolVersion: version.Latest, ServerAddress: "localhost", ServerPort: 25565, NextState: 2}},
+	{"StatusPing", Status, packet.StatusPing{Payload: 1234567890}},
+	{"LoginStart", Login, packet.LoginStart{Username: "Notch"}},
+	{"LoginSuccess", Login, packet.LoginSuccess{UUID: "069a79f4-44e9-4726-a5be-fca90e38aaf5", Username: "Notch"}},
+	{"KeepAlive", Play, packet.PlayKeepAlive{AliveID: 42}},
+	{"PositionAndLook", Play, packet.PlayPositionAndLook{X: 0.5, Y: 64, Z: -12.5, Yaw: 90, Pitch: 12}},
+	{"SystemChatMessage", Play, packet.PlaySystemChatMessage{
+		Content: codecs.Chat{Message: chat.Text("Hello, ").Bold().Message()},
+	}},
+	{"WindowItems", Play, packet.PlayWindowItems{
+		Count: 46,
+		Slots: make([]codecs.Slot, 46),
+	}},
+	{"ChunkData", Play, packet.PlayChunkData{
+		Heightmaps: codecs.Heightmaps{"MOTION_BLOCKING": make([]int64, 37)},
+		Data:       make(codecs.ByteArray, 24*8),
+		Light: packet.LightData{
+			SkyLightMask: codecs.BitSet{1<<26 - 1},
+			SkyLight:     byteArrays(26, 2048),
+		},
+	}},
+}
+
+// byteArrays will return n byte arrays of the size.
+func byteArrays(n, size int) []codecs.ByteArray {
+	arrays := make([]codecs.ByteArray, n)
+	for i := range arrays {
+		arrays[i] = make(codecs.ByteArray, size)
+	}
+
+	return arrays
+}
+
+func BenchmarkConnectionWrite(b *testing.B) {
+	for _, bp := range benchmarkPackets {
+		b.Run(bp.name, func(b *testing.B) {
+			c := &Connection{rw: &loopback{}, State: bp.state, Protocol: version.Latest}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Write(bp.packet); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkConnectionNext(b *testing.B) {
+	registry := &Registry{}
+	for _, bp := range benchmarkPackets {
+		registry.RegisterPacket(Serverbound, bp.state, bp.packet.ID(), reflect.TypeOf(bp.packet))
+	}
+
+	usedRegistryLock.Lock()
+	previous := usedRegistry
+	usedRegistry = registry
+	usedRegistryLock.Unlock()
+	defer func() {
+		usedRegistryLock.Lock()
+		usedRegistry = previous
+		usedRegistryLock.Unlock()
+	}()
+
+	for _, bp := range benchmarkPackets {
+		b.Run(bp.name, func(b *testing.B) {
+			var frames bytes.Buffer
+			w := &Connection{rw: nopCloser{&frames}, State: bp.state, Protocol: version.Latest}
+			if _, err := w.Write(bp.packet); err != nil {
+				b.Fatal(err)
+			}
+
+			c := &Connection{rw: &loopback{frames: frames.Bytes()}, State: bp.state, Protocol: version.Latest}
+			b.SetBytes(int64(frames.Len()))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Next(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type nopCloser struct {
+	io.ReadWriter
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,422 @@
+package protocol
+
+import (
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/util"
+)
+
+// Fields of a packet may carry an `mc` struct tag which changes the way they are
+// read and written. Options are separated by commas:
+//
+//	mc:"-"               the field is never read or written
+//	mc:"if=Action==0"    the field is only present if the condition holds
+//	mc:"len=Count"       the slice length is taken from the field Count instead of a VarInt prefix
+//	mc:"size=20"         the size of a codecs.SizedCodec such as codecs.FixedBitSet
+//	mc:"since=770"       the field is only present from the given protocol version on
+//	mc:"before=766"      the field is only present before the given protocol version
+//	mc:"max=16"          the maximum length of a codecs.LimitedCodec such as codecs.String, or of a slice
+//
+// Conditions refer to fields declared earlier in the same struct and support
+// ==, !=, & (any bit of the mask set) or a bare field name (field is non-zero).
+// Numbers may be written in decimal or with a 0x prefix.
+const fieldTagName = "mc"
+
+type fieldTag struct {
+	skip   bool
+	cond   string
+	length string
+	size   int
+	since  uint16
+	before uint16
+	max    int
+}
+
+func parseFieldTag(tag string) (fieldTag, error) {
+	var ft fieldTag
+	if tag == "" {
+		return ft, nil
+	}
+	if tag == "-" {
+		ft.skip = true
+		return ft, nil
+	}
+
+	for _, opt := range strings.Split(tag, ",") {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case strings.HasPrefix(opt, "if="):
+			ft.cond = strings.TrimPrefix(opt, "if=")
+		case strings.HasPrefix(opt, "len="):
+			ft.length = strings.TrimPrefix(opt, "len=")
+		case strings.HasPrefix(opt, "size="):
+			size, err := strconv.Atoi(strings.TrimPrefix(opt, "size="))
+			if err != nil || size < 0 {
+				return ft, ErrInvalidFieldTag
+			}
+			ft.size = size
+		case strings.HasPrefix(opt, "max="):
+			max, err := strconv.Atoi(strings.TrimPrefix(opt, "max="))
+			if err != nil || max <= 0 {
+				return ft, ErrInvalidFieldTag
+			}
+			ft.max = max
+		case strings.HasPrefix(opt, "since="):
+			since, err := strconv.ParseUint(strings.TrimPrefix(opt, "since="), 10, 16)
+			if err != nil {
+				return ft, ErrInvalidFieldTag
+			}
+			ft.since = uint16(since)
+		case strings.HasPrefix(opt, "before="):
+			before, err := strconv.ParseUint(strings.TrimPrefix(opt, "before="), 10, 16)
+			if err != nil {
+				return ft, ErrInvalidFieldTag
+			}
+			ft.before = uint16(before)
+		default:
+			return ft, ErrInvalidFieldTag
+		}
+	}
+
+	return ft, nil
+}
+
+// present reports whether the field is part of the packet for the protocol version.
+func (ft fieldTag) present(protocol uint16) bool {
+	return protocol >= ft.since && (ft.before == 0 || protocol < ft.before)
+}
+
+var codecType = reflect.TypeOf((*codecs.Codec)(nil)).Elem()
+
+// isComposite reports whether t is a struct made up entirely of codecs, slices
+// of codecs or other composite structs, which are written field by field.
+func isComposite(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t.Implements(codecType) {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get(fieldTagName) == "-" {
+			continue
+		}
+		if !isFieldType(f.Type) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isFieldType(t reflect.Type) bool {
+	if t.Implements(codecType) {
+		return true
+	}
+	if t.Kind() == reflect.Slice {
+		return isFieldType(t.Elem())
+	}
+
+	return isComposite(t)
+}
+
+func decodeStruct(r io.Reader, v reflect.Value, protocol uint16) error {
+	t := v.Type()
+
+	for i := 0; i < v.NumField(); i++ {
+		tag, err := parseFieldTag(t.Field(i).Tag.Get(fieldTagName))
+		if err != nil {
+			return err
+		}
+		if tag.skip || !tag.present(protocol) {
+			continue
+		}
+
+		if tag.cond != "" {
+			ok, err := evalCondition(v, tag.cond)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if err = decodeField(r, v, v.Field(i), tag, protocol); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeField(r io.Reader, parent, field reflect.Value, tag fieldTag, protocol uint16) error {
+	if codec, ok := field.Interface().(codecs.SizedCodec); ok && tag.size > 0 {
+		if err := setDecoded(field, codec.WithSize(tag.size)); err != nil {
+			return err
+		}
+	}
+	if codec, ok := field.Interface().(codecs.LimitedCodec); ok && tag.max > 0 {
+		value, err := codec.DecodeMax(r, tag.max)
+		if err != nil {
+			return err
+		}
+
+		return setDecoded(field, value)
+	}
+	if codec, ok := field.Interface().(codecs.VersionedCodec); ok {
+		value, err := codec.DecodeVersion(r, protocol)
+		if err != nil {
+			return err
+		}
+
+		return setDecoded(field, value)
+	}
+	if codec, ok := field.Interface().(codecs.Codec); ok {
+		value, err := codec.Decode(r)
+		if err != nil {
+			return err
+		}
+
+		return setDecoded(field, value)
+	}
+
+	switch {
+	case field.Kind() == reflect.Slice:
+		var (
+			length int
+			err    error
+		)
+		if tag.length != "" {
+			length, err = lengthOf(parent, tag.length)
+		} else {
+			length, err = util.ReadVarInt(r)
+		}
+		if err != nil {
+			return err
+		}
+		if length < 0 || (tag.max > 0 && length > tag.max) {
+			return ErrInvalidFieldLength
+		}
+		// Every element takes at least a byte, so a length above what is left
+		// of the packet cannot be right.
+		if l, ok := r.(interface{ Len() int }); ok && length > l.Len() {
+			return ErrInvalidFieldLength
+		}
+
+		// The slice grows as elements are read rather than trusting the length
+		// up front, for readers which do not tell how much is left.
+		slice := reflect.MakeSlice(field.Type(), 0, minInt(length, maxPreallocated))
+		for i := 0; i < length; i++ {
+			elem := reflect.New(field.Type().Elem()).Elem()
+			if err = decodeField(r, parent, elem, fieldTag{}, protocol); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+
+		field.Set(slice)
+		return nil
+	case isComposite(field.Type()):
+		return decodeStruct(r, field, protocol)
+	case field.Kind() == reflect.Struct:
+		_, err := codecs.JSON{V: field.Addr().Interface()}.Decode(r)
+		return err
+	}
+
+	return codecs.ErrUnknownCodecType
+}
+
+// setDecoded will set the field to the value a codec decoded.
+func setDecoded(field reflect.Value, value interface{}) error {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || !v.Type().ConvertibleTo(field.Type()) {
+		return ErrInvalidCodecValue
+	}
+
+	field.Set(v.Convert(field.Type()))
+	return nil
+}
+
+// maxPreallocated is the largest number of slice elements allocated before
+// they are read.
+const maxPreallocated = 1024
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func encodeStruct(w io.Writer, v reflect.Value, protocol uint16) error {
+	t := v.Type()
+
+	for i := 0; i < v.NumField(); i++ {
+		tag, err := parseFieldTag(t.Field(i).Tag.Get(fieldTagName))
+		if err != nil {
+			return err
+		}
+		if tag.skip || !tag.present(protocol) {
+			continue
+		}
+
+		if tag.cond != "" {
+			ok, err := evalCondition(v, tag.cond)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+
+		if err = encodeField(w, v, v.Field(i), tag, protocol); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeField(w io.Writer, parent, field reflect.Value, tag fieldTag, protocol uint16) error {
+	if codec, ok := field.Interface().(codecs.SizedCodec); ok && tag.size > 0 && codec.Len() != tag.size {
+		return ErrInvalidFieldLength
+	}
+	if codec, ok := field.Interface().(codecs.LimitedCodec); ok && tag.max > 0 {
+		return codec.EncodeMax(w, tag.max)
+	}
+	if codec, ok := field.Interface().(codecs.VersionedCodec); ok {
+		return codec.EncodeVersion(w, protocol)
+	}
+	if codec, ok := field.Interface().(codecs.Codec); ok {
+		return codec.Encode(w)
+	}
+
+	switch {
+	case field.Kind() == reflect.Slice:
+		if tag.max > 0 && field.Len() > tag.max {
+			return ErrInvalidFieldLength
+		}
+		if tag.length != "" {
+			length, err := lengthOf(parent, tag.length)
+			if err != nil {
+				return err
+			}
+			if length != field.Len() {
+				return ErrInvalidFieldLength
+			}
+		} else if err := util.WriteVarInt(w, field.Len()); err != nil {
+			return err
+		}
+
+		for i := 0; i < field.Len(); i++ {
+			if err := encodeField(w, parent, field.Index(i), fieldTag{}, protocol); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case isComposite(field.Type()):
+		return encodeStruct(w, field, protocol)
+	case field.Kind() == reflect.Struct:
+		return codecs.JSON{V: field.Interface()}.Encode(w)
+	}
+
+	return codecs.ErrUnknownCodecType
+}
+
+// lengthOf returns the value of the named field for use as a slice length.
+func lengthOf(v reflect.Value, name string) (int, error) {
+	field := v.FieldByName(name)
+	if !field.IsValid() {
+		return 0, ErrInvalidFieldTag
+	}
+
+	n, err := fieldInt(field)
+	return int(n), err
+}
+
+// evalCondition evaluates an `if=` condition against the fields of v.
+func evalCondition(v reflect.Value, cond string) (bool, error) {
+	for _, op := range []string{"==", "!=", "&"} {
+		idx := strings.Index(cond, op)
+		if idx < 0 {
+			continue
+		}
+
+		field := v.FieldByName(strings.TrimSpace(cond[:idx]))
+		if !field.IsValid() {
+			return false, ErrInvalidFieldTag
+		}
+		operand := strings.TrimSpace(cond[idx+len(op):])
+
+		switch field.Kind() {
+		case reflect.String:
+			switch op {
+			case "==":
+				return field.String() == operand, nil
+			case "!=":
+				return field.String() != operand, nil
+			}
+			return false, ErrInvalidFieldTag
+		case reflect.Bool:
+			b, err := strconv.ParseBool(operand)
+			if err != nil {
+				return false, ErrInvalidFieldTag
+			}
+			switch op {
+			case "==":
+				return field.Bool() == b, nil
+			case "!=":
+				return field.Bool() != b, nil
+			}
+			return false, ErrInvalidFieldTag
+		}
+
+		n, err := fieldInt(field)
+		if err != nil {
+			return false, err
+		}
+		m, err := strconv.ParseInt(operand, 0, 64)
+		if err != nil {
+			return false, ErrInvalidFieldTag
+		}
+
+		switch op {
+		case "==":
+			return n == m, nil
+		case "!=":
+			return n != m, nil
+		default:
+			return n&m != 0, nil
+		}
+	}
+
+	field := v.FieldByName(strings.TrimSpace(cond))
+	if !field.IsValid() {
+		return false, ErrInvalidFieldTag
+	}
+
+	return !field.IsZero(), nil
+}
+
+func fieldInt(field reflect.Value) (int64, error) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(field.Uint()), nil
+	case reflect.Bool:
+		if field.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	return 0, ErrInvalidFieldTag
+}
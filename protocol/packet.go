@@ -1,17 +1,23 @@
 package protocol
 
 import (
-	"bytes"
 	"errors"
+
+	"justanother.org/protocolhelper/util"
 )
 
-// Packet is a base packet.
+// Packet is a base packet. Data is taken from the buffer pool of util and is
+// released once the packet has been decoded.
 type Packet struct {
 	ID        int
 	Direction Direction
-	Data      bytes.Buffer
+	Data      *util.Buffer
 }
 
+// MaxPacketLength is the largest length of a packet, 2^21-1 as its length
+// prefix is at most three bytes long.
+const MaxPacketLength = 2097151
+
 // Direction is the direction of the packet
 type Direction int
 
@@ -25,4 +31,10 @@ const (
 var (
 	ErrUnknownPacketType   = errors.New("unknown packet type")
 	ErrInvalidPacketLength = errors.New("received packet is below zero or above maximum size")
+	ErrInvalidFieldTag     = errors.New("invalid mc struct tag")
+	ErrInvalidFieldLength  = errors.New("field length does not match its length field")
+	ErrInvalidCodecValue   = errors.New("codec decoded a value which does not fit its field")
+	ErrNoConfiguration     = errors.New("protocol version has no configuration state")
+	ErrInvalidState        = errors.New("connection is in the wrong state")
+	ErrUnsupportedProtocol = errors.New("protocol version is not supported")
 )
@@ -4,3 +4,31 @@ package packet
 type Holder interface {
 	ID() int
 }
+
+// VersionedHolder is implemented by packets whose ID depends on the protocol
+// version of the connection. IDVersion returns -1 for versions without the
+// packet, and ID returns the ID of version.Latest, or of the last version
+// having the packet.
+type VersionedHolder interface {
+	Holder
+	IDVersion(protocol uint16) int
+}
+
+// versionID is the ID of a packet from a protocol version on.
+type versionID struct {
+	since uint16
+	id    int
+}
+
+// idSince will return the ID of the last entry of ids, ordered by version,
+// which the protocol version is at or after, or -1 if it is before all of them.
+func idSince(protocol uint16, ids ...versionID) int {
+	id := -1
+	for _, v := range ids {
+		if protocol >= v.since {
+			id = v.id
+		}
+	}
+
+	return id
+}
@@ -0,0 +1,407 @@
+package packet
+
+import (
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// The configuration state was added in 1.20.2. The packets below use the
+// layout of 1.21.5 and follow older versions through their fields, ID
+// returns their ID of 1.21.5 and IDVersion that of the connection.
+
+// KnownPack is a data pack the server and the client both know.
+type KnownPack struct {
+	Namespace codecs.String
+	ID        codecs.String
+	Version   codecs.String
+}
+
+// TagRegistry holds the tags of a registry.
+type TagRegistry struct {
+	Registry codecs.String
+	Tags     []Tag
+}
+
+// Tag is a named list of registry entry IDs.
+type Tag struct {
+	Name    codecs.String
+	Entries []codecs.VarInt
+}
+
+// ReportDetail is a detail added to crash reports of the client.
+type ReportDetail struct {
+	Title       codecs.String `mc:"max=128"`
+	Description codecs.String `mc:"max=4096"`
+}
+
+// ServerLink is a link shown in the pause menu, labelled either with a
+// built-in label or with a component.
+type ServerLink struct {
+	IsBuiltin    codecs.Boolean
+	BuiltinLabel codecs.VarInt `mc:"if=IsBuiltin"`
+	Label        codecs.Chat   `mc:"if=IsBuiltin==false"`
+	URL          codecs.String
+}
+
+// ConfigCookieRequest represents a packet
+type ConfigCookieRequest struct {
+	Key codecs.String
+}
+
+// ID returns the packet ID
+func (p ConfigCookieRequest) ID() int { return 0x00 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigCookieRequest) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x00})
+}
+
+// ConfigClientboundPluginMessage represents a packet
+type ConfigClientboundPluginMessage struct {
+	Channel codecs.String
+	Data    codecs.RawData
+}
+
+// ID returns the packet ID
+func (p ConfigClientboundPluginMessage) ID() int { return 0x01 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigClientboundPluginMessage) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x00}, versionID{version.V1_20_5, 0x01})
+}
+
+// ConfigDisconnect represents a packet
+type ConfigDisconnect struct {
+	Reason codecs.Chat
+}
+
+// ID returns the packet ID
+func (p ConfigDisconnect) ID() int { return 0x02 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigDisconnect) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x01}, versionID{version.V1_20_5, 0x02})
+}
+
+// ConfigFinish represents a packet
+type ConfigFinish struct{}
+
+// ID returns the packet ID
+func (p ConfigFinish) ID() int { return 0x03 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigFinish) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x02}, versionID{version.V1_20_5, 0x03})
+}
+
+// ConfigKeepAlive represents a packet
+type ConfigKeepAlive struct {
+	AliveID codecs.Long
+}
+
+// ID returns the packet ID
+func (p ConfigKeepAlive) ID() int { return 0x04 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigKeepAlive) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x03}, versionID{version.V1_20_5, 0x04})
+}
+
+// ConfigPing represents a packet
+type ConfigPing struct {
+	PingID codecs.Int
+}
+
+// ID returns the packet ID
+func (p ConfigPing) ID() int { return 0x05 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigPing) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x04}, versionID{version.V1_20_5, 0x05})
+}
+
+// ConfigResetChat represents a packet
+type ConfigResetChat struct{}
+
+// ID returns the packet ID
+func (p ConfigResetChat) ID() int { return 0x06 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigResetChat) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x06})
+}
+
+// ConfigRemoveResourcePack represents a packet
+type ConfigRemoveResourcePack struct {
+	HasUUID codecs.Boolean
+	UUID    codecs.UUID `mc:"if=HasUUID"`
+}
+
+// ID returns the packet ID
+func (p ConfigRemoveResourcePack) ID() int { return 0x08 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigRemoveResourcePack) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_3, 0x06}, versionID{version.V1_20_5, 0x08})
+}
+
+// ConfigAddResourcePack represents a packet
+type ConfigAddResourcePack struct {
+	UUID          codecs.UUID `mc:"since=765"`
+	URL           codecs.String
+	Hash          codecs.String `mc:"max=40"`
+	Forced        codecs.Boolean
+	HasPromptText codecs.Boolean
+	PromptText    codecs.Chat `mc:"if=HasPromptText"`
+}
+
+// ID returns the packet ID
+func (p ConfigAddResourcePack) ID() int { return 0x09 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigAddResourcePack) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x06}, versionID{version.V1_20_3, 0x07}, versionID{version.V1_20_5, 0x09})
+}
+
+// ConfigStoreCookie represents a packet
+type ConfigStoreCookie struct {
+	Key     codecs.String
+	Payload codecs.ByteArray `mc:"max=5120"`
+}
+
+// ID returns the packet ID
+func (p ConfigStoreCookie) ID() int { return 0x0A }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigStoreCookie) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x0A})
+}
+
+// ConfigTransfer represents a packet
+type ConfigTransfer struct {
+	Host codecs.String
+	Port codecs.VarInt
+}
+
+// ID returns the packet ID
+func (p ConfigTransfer) ID() int { return 0x0B }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigTransfer) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x0B})
+}
+
+// ConfigFeatureFlags represents a packet
+type ConfigFeatureFlags struct {
+	Flags []codecs.String
+}
+
+// ID returns the packet ID
+func (p ConfigFeatureFlags) ID() int { return 0x0C }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigFeatureFlags) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x07}, versionID{version.V1_20_3, 0x08}, versionID{version.V1_20_5, 0x0C})
+}
+
+// ConfigUpdateTags represents a packet
+type ConfigUpdateTags struct {
+	Registries []TagRegistry
+}
+
+// ID returns the packet ID
+func (p ConfigUpdateTags) ID() int { return 0x0D }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigUpdateTags) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x08}, versionID{version.V1_20_3, 0x09}, versionID{version.V1_20_5, 0x0D})
+}
+
+// ConfigClientboundKnownPacks represents a packet
+type ConfigClientboundKnownPacks struct {
+	Packs []KnownPack
+}
+
+// ID returns the packet ID
+func (p ConfigClientboundKnownPacks) ID() int { return 0x0E }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigClientboundKnownPacks) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x0E})
+}
+
+// ConfigCustomReportDetails represents a packet
+type ConfigCustomReportDetails struct {
+	Details []ReportDetail
+}
+
+// ID returns the packet ID
+func (p ConfigCustomReportDetails) ID() int { return 0x0F }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigCustomReportDetails) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_21, 0x0F})
+}
+
+// ConfigServerLinks represents a packet
+type ConfigServerLinks struct {
+	Links []ServerLink
+}
+
+// ID returns the packet ID
+func (p ConfigServerLinks) ID() int { return 0x10 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigServerLinks) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_21, 0x10})
+}
+
+// ConfigClientInformation represents a packet
+type ConfigClientInformation struct {
+	Locale              codecs.String `mc:"max=16"`
+	ViewDistance        codecs.Byte
+	ChatMode            codecs.VarInt
+	ChatColors          codecs.Boolean
+	DisplayedSkinParts  codecs.UnsignedByte
+	MainHand            codecs.VarInt
+	EnableTextFiltering codecs.Boolean
+	AllowServerListings codecs.Boolean
+	ParticleStatus      codecs.VarInt `mc:"since=768"`
+}
+
+// ID returns the packet ID
+func (p ConfigClientInformation) ID() int { return 0x00 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigClientInformation) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x00})
+}
+
+// ConfigCookieResponse represents a packet
+type ConfigCookieResponse struct {
+	Key        codecs.String
+	HasPayload codecs.Boolean
+	Payload    codecs.ByteArray `mc:"if=HasPayload,max=5120"`
+}
+
+// ID returns the packet ID
+func (p ConfigCookieResponse) ID() int { return 0x01 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigCookieResponse) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x01})
+}
+
+// ConfigServerboundPluginMessage represents a packet
+type ConfigServerboundPluginMessage struct {
+	Channel codecs.String
+	Data    codecs.RawData
+}
+
+// ID returns the packet ID
+func (p ConfigServerboundPluginMessage) ID() int { return 0x02 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigServerboundPluginMessage) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x01}, versionID{version.V1_20_5, 0x02})
+}
+
+// ConfigAcknowledgeFinish represents a packet
+type ConfigAcknowledgeFinish struct{}
+
+// ID returns the packet ID
+func (p ConfigAcknowledgeFinish) ID() int { return 0x03 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigAcknowledgeFinish) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x02}, versionID{version.V1_20_5, 0x03})
+}
+
+// ConfigPong represents a packet
+type ConfigPong struct {
+	PingID codecs.Int
+}
+
+// ID returns the packet ID
+func (p ConfigPong) ID() int { return 0x05 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigPong) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x04}, versionID{version.V1_20_5, 0x05})
+}
+
+// Results of a ConfigResourcePackResponse.
+const (
+	ResourcePackLoaded = iota
+	ResourcePackDeclined
+	ResourcePackFailedDownload
+	ResourcePackAccepted
+	ResourcePackDownloaded
+	ResourcePackInvalidURL
+	ResourcePackFailedReload
+	ResourcePackDiscarded
+)
+
+// ConfigResourcePackResponse represents a packet
+type ConfigResourcePackResponse struct {
+	UUID   codecs.UUID `mc:"since=765"`
+	Result codecs.VarInt
+}
+
+// ID returns the packet ID
+func (p ConfigResourcePackResponse) ID() int { return 0x06 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigResourcePackResponse) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x05}, versionID{version.V1_20_5, 0x06})
+}
+
+// ConfigServerboundKnownPacks represents a packet
+type ConfigServerboundKnownPacks struct {
+	Packs []KnownPack
+}
+
+// ID returns the packet ID
+func (p ConfigServerboundKnownPacks) ID() int { return 0x07 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigServerboundKnownPacks) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x07})
+}
+
+// RegistryEntry is an entry of a ConfigRegistryData packet. Its data is left
+// out when the client knows it from a known pack.
+type RegistryEntry struct {
+	ID      codecs.String
+	HasData codecs.Boolean
+	Data    codecs.NBT `mc:"if=HasData"`
+}
+
+// ConfigRegistryData represents a packet, sending a registry since 1.20.5
+type ConfigRegistryData struct {
+	RegistryID codecs.String
+	Entries    []RegistryEntry
+}
+
+// ID returns the packet ID
+func (p ConfigRegistryData) ID() int { return 0x07 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigRegistryData) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_5, 0x07})
+}
+
+// ConfigRegistryCodec represents a packet, the Registry Data packet of 1.20.2
+// to 1.20.4 which sends every registry at once as the compound Codec. It does
+// not exist in later versions, ID returns its ID of 1.20.2.
+type ConfigRegistryCodec struct {
+	Codec codecs.NBT
+}
+
+// ID returns the packet ID
+func (p ConfigRegistryCodec) ID() int { return 0x05 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p ConfigRegistryCodec) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x05}, versionID{version.V1_20_5, -1})
+}
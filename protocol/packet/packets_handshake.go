@@ -5,7 +5,7 @@ import "justanother.org/protocolhelper/protocol/codecs"
 // Handshake represents a packet
 type Handshake struct {
 	ProtocolVersion codecs.VarInt
-	ServerAddress   codecs.String
+	ServerAddress   codecs.String `mc:"max=255"`
 	ServerPort      codecs.UnsignedShort
 	NextState       codecs.VarInt
 }
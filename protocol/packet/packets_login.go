@@ -3,11 +3,12 @@ package packet
 import (
 	"justanother.org/protocolhelper/chat"
 	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/version"
 )
 
 // LoginStart represents a packet
 type LoginStart struct {
-	Username codecs.String
+	Username codecs.String `mc:"max=16"`
 }
 
 // ID returns the packet ID
@@ -15,8 +16,8 @@ func (p LoginStart) ID() int { return 0x00 }
 
 // LoginSuccess represents a packet
 type LoginSuccess struct {
-	UUID     codecs.String
-	Username codecs.String
+	UUID     codecs.String `mc:"max=36"`
+	Username codecs.String `mc:"max=16"`
 }
 
 // ID returns the packet ID
@@ -29,3 +30,14 @@ type LoginDisconnect struct {
 
 // ID returns the packet ID
 func (p LoginDisconnect) ID() int { return 0x00 }
+
+// LoginAcknowledged represents a packet, sent since 1.20.2
+type LoginAcknowledged struct{}
+
+// ID returns the packet ID
+func (p LoginAcknowledged) ID() int { return 0x03 }
+
+// IDVersion returns the packet ID of the protocol version
+func (p LoginAcknowledged) IDVersion(protocol uint16) int {
+	return idSince(protocol, versionID{version.V1_20_2, 0x03})
+}
@@ -1,8 +1,8 @@
 package packet
 
 import (
-	"justanother.org/protocolhelper/chat"
 	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/version"
 )
 
 // PlayKeepAlive represents a packet
@@ -14,11 +14,8 @@ type PlayKeepAlive struct {
 func (p PlayKeepAlive) ID() int { return 0x1F }
 
 // PlayChatMessage represents a packet
-// TODO: Verify that chat.TextComponent is being read correctly.
-//  Even though we don't plan on using these types, we will want
-//  to have a good example.
 type PlayChatMessage struct {
-	Chat     chat.TextComponent
+	Chat     codecs.Chat
 	Position codecs.Byte
 }
 
@@ -39,6 +36,72 @@ type PlayJoinGame struct {
 // ID returns the packet ID
 func (p PlayJoinGame) ID() int { return 0x23 }
 
+// PlayJoinGameWithCodec represents a packet, the Join Game packet of 1.16.2 to
+// 1.20.1 which carries the registry codec. Its fields follow the protocol
+// version, but its ID is that of 1.19.4 to 1.20.1, the packet had other IDs
+// before.
+type PlayJoinGameWithCodec struct {
+	EntityID         codecs.Int
+	IsHardcore       codecs.Boolean
+	Gamemode         codecs.UnsignedByte
+	PreviousGamemode codecs.Byte
+	WorldNames       []codecs.String
+	DimensionCodec   codecs.NBT
+	Dimension        codecs.NBT    `mc:"before=759"`
+	DimensionType    codecs.String `mc:"since=759"`
+	WorldName        codecs.String
+	HashedSeed       codecs.Long
+	MaxPlayers       codecs.VarInt
+	ViewDistance     codecs.VarInt
+	// SimulationDistance is sent since 1.18.
+	SimulationDistance  codecs.VarInt `mc:"since=757"`
+	ReducedDebugInfo    codecs.Boolean
+	EnableRespawnScreen codecs.Boolean
+	IsDebug             codecs.Boolean
+	IsFlat              codecs.Boolean
+
+	HasDeathLocation   codecs.Boolean  `mc:"since=759"`
+	DeathDimensionName codecs.String   `mc:"since=759,if=HasDeathLocation"`
+	DeathLocation      codecs.Position `mc:"since=759,if=HasDeathLocation"`
+	PortalCooldown     codecs.VarInt   `mc:"since=763"`
+}
+
+// ID returns the packet ID
+func (p PlayJoinGameWithCodec) ID() int { return 0x28 }
+
+// PlayLogin represents a packet, the Join Game packet since 1.20.2. The
+// registries are sent during the configuration state instead.
+type PlayLogin struct {
+	EntityID            codecs.Int
+	IsHardcore          codecs.Boolean
+	DimensionNames      []codecs.String
+	MaxPlayers          codecs.VarInt
+	ViewDistance        codecs.VarInt
+	SimulationDistance  codecs.VarInt
+	ReducedDebugInfo    codecs.Boolean
+	EnableRespawnScreen codecs.Boolean
+	DoLimitedCrafting   codecs.Boolean
+	DimensionType       codecs.String `mc:"before=766"`
+	// DimensionTypeID is the ID of the dimension type in its registry.
+	DimensionTypeID  codecs.VarInt `mc:"since=766"`
+	DimensionName    codecs.String
+	HashedSeed       codecs.Long
+	GameMode         codecs.UnsignedByte
+	PreviousGameMode codecs.Byte
+	IsDebug          codecs.Boolean
+	IsFlat           codecs.Boolean
+
+	HasDeathLocation   codecs.Boolean
+	DeathDimensionName codecs.String   `mc:"if=HasDeathLocation"`
+	DeathLocation      codecs.Position `mc:"if=HasDeathLocation"`
+	PortalCooldown     codecs.VarInt
+	SeaLevel           codecs.VarInt  `mc:"since=768"`
+	EnforcesSecureChat codecs.Boolean `mc:"since=766"`
+}
+
+// ID returns the packet ID
+func (p PlayLogin) ID() int { return 0x2B }
+
 // PlaySpawnPosition represents a packet
 type PlaySpawnPosition struct {
 	Location codecs.Long
@@ -60,3 +123,243 @@ type PlayPositionAndLook struct {
 
 // ID returns the packet ID
 func (p PlayPositionAndLook) ID() int { return 0x2E }
+
+// PlayWindowItems represents a packet
+type PlayWindowItems struct {
+	WindowID codecs.UnsignedByte
+	Count    codecs.Short
+	Slots    []codecs.Slot `mc:"len=Count"`
+}
+
+// ID returns the packet ID
+func (p PlayWindowItems) ID() int { return 0x14 }
+
+// PlaySetSlot represents a packet
+type PlaySetSlot struct {
+	WindowID codecs.Byte
+	Slot     codecs.Short
+	SlotData codecs.Slot
+}
+
+// ID returns the packet ID
+func (p PlaySetSlot) ID() int { return 0x16 }
+
+// PlayCreativeInventoryAction represents a packet
+type PlayCreativeInventoryAction struct {
+	Slot        codecs.Short
+	ClickedItem codecs.Slot
+}
+
+// ID returns the packet ID
+func (p PlayCreativeInventoryAction) ID() int { return 0x1B }
+
+// The signed chat packets below use the layout introduced in 1.19.3 and the
+// packet IDs of 1.21.5.
+
+// PackedSignature is a message signature sent to a client, either as the
+// index plus one of a signature the client has cached or, when ID is zero,
+// in full.
+type PackedSignature struct {
+	ID        codecs.VarInt
+	Signature codecs.Signature `mc:"if=ID==0"`
+}
+
+// ArgumentSignature is the signature of a signed argument of a chat command.
+type ArgumentSignature struct {
+	Name      codecs.String `mc:"max=16"`
+	Signature codecs.Signature
+}
+
+// Filter types of a PlayPlayerChatMessage.
+const (
+	FilterPassThrough = iota
+	FilterFullyFiltered
+	FilterPartiallyFiltered
+)
+
+// PlayPlayerChatMessage represents a packet
+type PlayPlayerChatMessage struct {
+	GlobalIndex  codecs.VarInt `mc:"since=770"`
+	Sender       codecs.UUID
+	Index        codecs.VarInt
+	HasSignature codecs.Boolean
+	Signature    codecs.Signature `mc:"if=HasSignature"`
+
+	Message          codecs.String `mc:"max=256"`
+	Timestamp        codecs.Long
+	Salt             codecs.Long
+	PreviousMessages []PackedSignature
+
+	HasUnsignedContent codecs.Boolean
+	UnsignedContent    codecs.Chat `mc:"if=HasUnsignedContent"`
+	FilterType         codecs.VarInt
+	FilterTypeBits     codecs.BitSet `mc:"if=FilterType==2"`
+
+	// ChatType is the chat_type registry ID, plus one since 1.20.5.
+	ChatType      codecs.VarInt
+	SenderName    codecs.Chat
+	HasTargetName codecs.Boolean
+	TargetName    codecs.Chat `mc:"if=HasTargetName"`
+}
+
+// ID returns the packet ID
+func (p PlayPlayerChatMessage) ID() int { return 0x3A }
+
+// PlaySystemChatMessage represents a packet
+type PlaySystemChatMessage struct {
+	Content codecs.Chat
+	Overlay codecs.Boolean
+}
+
+// ID returns the packet ID
+func (p PlaySystemChatMessage) ID() int { return 0x72 }
+
+// PlayDisguisedChatMessage represents a packet
+type PlayDisguisedChatMessage struct {
+	Message codecs.Chat
+
+	// ChatType is the chat_type registry ID, plus one since 1.20.5.
+	ChatType      codecs.VarInt
+	SenderName    codecs.Chat
+	HasTargetName codecs.Boolean
+	TargetName    codecs.Chat `mc:"if=HasTargetName"`
+}
+
+// ID returns the packet ID
+func (p PlayDisguisedChatMessage) ID() int { return 0x1C }
+
+// PlaySendChatMessage represents a packet
+type PlaySendChatMessage struct {
+	Message      codecs.String `mc:"max=256"`
+	Timestamp    codecs.Long
+	Salt         codecs.Long
+	HasSignature codecs.Boolean
+	Signature    codecs.Signature `mc:"if=HasSignature"`
+	MessageCount codecs.VarInt
+	Acknowledged codecs.FixedBitSet `mc:"size=20"`
+	Checksum     codecs.Byte        `mc:"since=770"`
+}
+
+// ID returns the packet ID
+func (p PlaySendChatMessage) ID() int { return 0x07 }
+
+// PlaySendChatCommand represents a packet
+type PlaySendChatCommand struct {
+	Command codecs.String
+}
+
+// ID returns the packet ID
+func (p PlaySendChatCommand) ID() int { return 0x05 }
+
+// PlaySignedChatCommand represents a packet
+type PlaySignedChatCommand struct {
+	Command            codecs.String
+	Timestamp          codecs.Long
+	Salt               codecs.Long
+	ArgumentSignatures []ArgumentSignature
+	MessageCount       codecs.VarInt
+	Acknowledged       codecs.FixedBitSet `mc:"size=20"`
+	Checksum           codecs.Byte        `mc:"since=770"`
+}
+
+// ID returns the packet ID
+func (p PlaySignedChatCommand) ID() int { return 0x06 }
+
+// PlayMessageAcknowledgment represents a packet
+type PlayMessageAcknowledgment struct {
+	MessageCount codecs.VarInt
+}
+
+// ID returns the packet ID
+func (p PlayMessageAcknowledgment) ID() int { return 0x04 }
+
+// PlayPlayerSession represents a packet
+type PlayPlayerSession struct {
+	SessionID    codecs.UUID
+	ExpiresAt    codecs.Long
+	PublicKey    codecs.ByteArray `mc:"max=512"`
+	KeySignature codecs.ByteArray `mc:"max=4096"`
+}
+
+// ID returns the packet ID
+func (p PlayPlayerSession) ID() int { return 0x08 }
+
+// PlayStartConfiguration represents a packet, sent since 1.20.2. ID returns
+// its ID of 1.21.5 and IDVersion that of the connection.
+type PlayStartConfiguration struct{}
+
+// ID returns the packet ID
+func (p PlayStartConfiguration) ID() int { return 0x6F }
+
+// IDVersion returns the packet ID of the protocol version
+func (p PlayStartConfiguration) IDVersion(protocol uint16) int {
+	return idSince(protocol,
+		versionID{version.V1_20_2, 0x65},
+		versionID{version.V1_20_3, 0x67},
+		versionID{version.V1_20_5, 0x69},
+		versionID{version.V1_21_2, 0x70},
+		versionID{version.V1_21_5, 0x6F},
+	)
+}
+
+// PlayAcknowledgeConfiguration represents a packet, sent since 1.20.2. ID
+// returns its ID of 1.21.5 and IDVersion that of the connection.
+type PlayAcknowledgeConfiguration struct{}
+
+// ID returns the packet ID
+func (p PlayAcknowledgeConfiguration) ID() int { return 0x0E }
+
+// IDVersion returns the packet ID of the protocol version
+func (p PlayAcknowledgeConfiguration) IDVersion(protocol uint16) int {
+	return idSince(protocol,
+		versionID{version.V1_20_2, 0x0B},
+		versionID{version.V1_20_5, 0x0C},
+		versionID{version.V1_21_2, 0x0E},
+	)
+}
+
+// LightData is the light of a chunk column, sent in the Chunk Data and
+// Update Light packets. Bit i of the masks stands for the section i-1 of the
+// column, counting the sections below and above the world.
+type LightData struct {
+	TrustEdges          codecs.Boolean `mc:"before=763"`
+	SkyLightMask        codecs.BitSet
+	BlockLightMask      codecs.BitSet
+	EmptySkyLightMask   codecs.BitSet
+	EmptyBlockLightMask codecs.BitSet
+	SkyLight            []codecs.ByteArray
+	BlockLight          []codecs.ByteArray
+}
+
+// ChunkBlockEntity is a block entity of a chunk, its X and Z packed into
+// the high and low nibbles of PackedXZ.
+type ChunkBlockEntity struct {
+	PackedXZ codecs.UnsignedByte
+	Y        codecs.Short
+	Type     codecs.VarInt
+	Data     codecs.NBT
+}
+
+// PlayChunkData represents a packet, the Chunk Data and Update Light packet
+// of 1.18 and later
+type PlayChunkData struct {
+	ChunkX        codecs.Int
+	ChunkZ        codecs.Int
+	Heightmaps    codecs.Heightmaps
+	Data          codecs.ByteArray
+	BlockEntities []ChunkBlockEntity
+	Light         LightData
+}
+
+// ID returns the packet ID
+func (p PlayChunkData) ID() int { return 0x27 }
+
+// PlayUpdateLight represents a packet
+type PlayUpdateLight struct {
+	ChunkX codecs.VarInt
+	ChunkZ codecs.VarInt
+	Light  LightData
+}
+
+// ID returns the packet ID
+func (p PlayUpdateLight) ID() int { return 0x2A }
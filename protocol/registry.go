@@ -3,6 +3,8 @@ package protocol
 import (
 	"reflect"
 	"sync"
+
+	"justanother.org/protocolhelper/protocol/packet"
 )
 
 // Version represents our version.
@@ -32,10 +34,31 @@ func GetPacketType(direction Direction, state State, id int) (reflect.Type, erro
 	return nil, ErrUnknownPacketType
 }
 
+// getPacketTypeVersion will return the packet of the ID in the protocol
+// version. Versioned packets are looked up before the others, which carry
+// the IDs of the version the application was written for.
+func getPacketTypeVersion(direction Direction, state State, id int, protocol uint16) (reflect.Type, error) {
+	usedRegistryLock.Lock()
+	var versioned []reflect.Type
+	if usedRegistry != nil {
+		versioned = usedRegistry.versioned[direction][state]
+	}
+	usedRegistryLock.Unlock()
+
+	for _, typ := range versioned {
+		if reflect.Zero(typ).Interface().(packet.VersionedHolder).IDVersion(protocol) == id {
+			return typ, nil
+		}
+	}
+
+	return GetPacketType(direction, state, id)
+}
+
 // Registry will help manage the registration of packets.
 type Registry struct {
-	checker func(string) bool
-	packets map[Direction]map[State]map[int]reflect.Type
+	checker   func(string) bool
+	packets   map[Direction]map[State]map[int]reflect.Type
+	versioned map[Direction]map[State][]reflect.Type
 }
 
 func (registry *Registry) validatePacketMap(direction Direction, state State) {
@@ -61,6 +84,19 @@ func (registry *Registry) RegisterPacket(direction Direction, state State, id in
 	registry.packets[direction][state][id] = packet
 }
 
+// RegisterVersionedPacket will register the packet to the registry under
+// the IDs it has in each protocol version, which do not collide with the
+// packets registered by RegisterPacket.
+func (registry *Registry) RegisterVersionedPacket(direction Direction, state State, h packet.VersionedHolder) {
+	if registry.versioned == nil {
+		registry.versioned = make(map[Direction]map[State][]reflect.Type)
+	}
+	if _, ok := registry.versioned[direction]; !ok {
+		registry.versioned[direction] = make(map[State][]reflect.Type)
+	}
+	registry.versioned[direction][state] = append(registry.versioned[direction][state], reflect.TypeOf(h))
+}
+
 // Submit will submit the registry for the server to use.
 func (registry *Registry) Submit() {
 	usedRegistryLock.Lock()
@@ -78,3 +114,48 @@ func (registry *Registry) Submit() {
 func NewRegistry(checker func(version string) (coverage bool)) *Registry {
 	return &Registry{checker: checker}
 }
+
+// RegisterConfiguration will register the packets of the configuration state,
+// together with the login and play packets entering and leaving it. They are
+// registered under the IDs of the protocol version of each connection.
+func (registry *Registry) RegisterConfiguration() {
+	for _, h := range []packet.VersionedHolder{
+		packet.ConfigCookieRequest{},
+		packet.ConfigClientboundPluginMessage{},
+		packet.ConfigDisconnect{},
+		packet.ConfigFinish{},
+		packet.ConfigKeepAlive{},
+		packet.ConfigPing{},
+		packet.ConfigResetChat{},
+		packet.ConfigRegistryCodec{},
+		packet.ConfigRegistryData{},
+		packet.ConfigRemoveResourcePack{},
+		packet.ConfigAddResourcePack{},
+		packet.ConfigStoreCookie{},
+		packet.ConfigTransfer{},
+		packet.ConfigFeatureFlags{},
+		packet.ConfigUpdateTags{},
+		packet.ConfigClientboundKnownPacks{},
+		packet.ConfigCustomReportDetails{},
+		packet.ConfigServerLinks{},
+	} {
+		registry.RegisterVersionedPacket(Clientbound, Configuration, h)
+	}
+
+	for _, h := range []packet.VersionedHolder{
+		packet.ConfigClientInformation{},
+		packet.ConfigCookieResponse{},
+		packet.ConfigServerboundPluginMessage{},
+		packet.ConfigAcknowledgeFinish{},
+		packet.ConfigKeepAlive{},
+		packet.ConfigPong{},
+		packet.ConfigResourcePackResponse{},
+		packet.ConfigServerboundKnownPacks{},
+	} {
+		registry.RegisterVersionedPacket(Serverbound, Configuration, h)
+	}
+
+	registry.RegisterVersionedPacket(Serverbound, Login, packet.LoginAcknowledged{})
+	registry.RegisterVersionedPacket(Clientbound, Play, packet.PlayStartConfiguration{})
+	registry.RegisterVersionedPacket(Serverbound, Play, packet.PlayAcknowledgeConfiguration{})
+}
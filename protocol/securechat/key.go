@@ -0,0 +1,67 @@
+package securechat
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"time"
+
+	"justanother.org/protocolhelper/protocol/packet"
+)
+
+// PublicKey is the key a player signs chat messages with, itself signed by Mojang.
+type PublicKey struct {
+	ExpiresAt time.Time
+	Key       *rsa.PublicKey
+
+	// Encoded is the key in X.509 DER form, as sent by the client.
+	Encoded []byte
+
+	// Signature is Mojang's signature of the key.
+	Signature []byte
+}
+
+// ParsePublicKey will read the public key of a chat session update.
+func ParsePublicKey(p packet.PlayPlayerSession) (PublicKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(p.PublicKey)
+	if err != nil {
+		return PublicKey{}, ErrInvalidKey
+	}
+	key, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return PublicKey{}, ErrInvalidKey
+	}
+
+	return PublicKey{
+		ExpiresAt: time.UnixMilli(int64(p.ExpiresAt)),
+		Key:       key,
+		Encoded:   p.PublicKey,
+		Signature: p.KeySignature,
+	}, nil
+}
+
+// Expired will report whether the key has expired at t.
+func (k PublicKey) Expired(t time.Time) bool {
+	return !t.Before(k.ExpiresAt)
+}
+
+// Verify will check that the key was issued to player by one of the trusted
+// keys, usually the profile property keys published by Mojang at
+// https://api.minecraftservices.com/publickeys.
+func (k PublicKey) Verify(player [16]byte, trusted []*rsa.PublicKey) error {
+	h := sha1.New()
+	h.Write(player[:])
+	binary.Write(h, binary.BigEndian, k.ExpiresAt.UnixMilli())
+	h.Write(k.Encoded)
+
+	digest := h.Sum(nil)
+	for _, t := range trusted {
+		if rsa.VerifyPKCS1v15(t, crypto.SHA1, digest, k.Signature) == nil {
+			return nil
+		}
+	}
+
+	return ErrInvalidKeySignature
+}
@@ -0,0 +1,164 @@
+package securechat
+
+import (
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/packet"
+)
+
+// LastSeenCount is the number of messages a client acknowledges at most.
+const LastSeenCount = 20
+
+// SignatureCacheSize is the number of signatures a client caches, which lets
+// the server refer to them by index.
+const SignatureCacheSize = 128
+
+// trackedMessage is a message sent to a client, waiting for or acknowledged by it.
+type trackedMessage struct {
+	signature codecs.Signature
+	pending   bool
+}
+
+// LastSeenValidator tracks the signed messages sent to a client and checks
+// the acknowledgements the client sends back, mirroring the client's own list
+// of the last seen messages.
+type LastSeenValidator struct {
+	tracked     []*trackedMessage
+	lastPending *codecs.Signature
+}
+
+// NewLastSeenValidator will create a validator with no messages sent.
+func NewLastSeenValidator() *LastSeenValidator {
+	return &LastSeenValidator{tracked: make([]*trackedMessage, LastSeenCount)}
+}
+
+// AddPending will track a signed message sent to the client.
+func (v *LastSeenValidator) AddPending(signature codecs.Signature) {
+	if v.lastPending != nil && *v.lastPending == signature {
+		return
+	}
+
+	v.tracked = append(v.tracked, &trackedMessage{signature: signature, pending: true})
+	v.lastPending = &signature
+}
+
+// Pending will return the number of messages the client has not acknowledged yet.
+func (v *LastSeenValidator) Pending() int {
+	return len(v.tracked) - LastSeenCount
+}
+
+// ApplyOffset will drop the oldest offset tracked messages, which the client
+// reports to have moved past.
+func (v *LastSeenValidator) ApplyOffset(offset int) error {
+	if offset < 0 || offset > v.Pending() {
+		return ErrInvalidAcknowledgment
+	}
+
+	v.tracked = v.tracked[offset:]
+	return nil
+}
+
+// ApplyUpdate will apply the acknowledgements sent along with a chat message
+// or signed command and return the signatures of the messages the client has
+// seen, oldest first. A non-zero checksum, sent since 1.21.5, has to match
+// that of the signatures.
+func (v *LastSeenValidator) ApplyUpdate(offset int, acknowledged codecs.FixedBitSet, checksum int8) ([]codecs.Signature, error) {
+	if err := v.ApplyOffset(offset); err != nil {
+		return nil, err
+	}
+	if acknowledged.Length() > LastSeenCount {
+		return nil, ErrInvalidAcknowledgment
+	}
+
+	var seen []codecs.Signature
+	for i := 0; i < LastSeenCount; i++ {
+		entry := v.tracked[i]
+		if acknowledged.Get(i) {
+			if entry == nil {
+				return nil, ErrInvalidAcknowledgment
+			}
+
+			v.tracked[i] = &trackedMessage{signature: entry.signature}
+			seen = append(seen, entry.signature)
+		} else {
+			if entry != nil && !entry.pending {
+				return nil, ErrInvalidAcknowledgment
+			}
+			v.tracked[i] = nil
+		}
+	}
+
+	if checksum != 0 && checksum != Checksum(seen) {
+		return nil, ErrInvalidChecksum
+	}
+
+	return seen, nil
+}
+
+// Checksum will return the checksum of a list of last seen signatures, which
+// is never zero.
+func Checksum(signatures []codecs.Signature) int8 {
+	sum := int32(1)
+	for _, s := range signatures {
+		sum = 31*sum + signatureHash(s)
+	}
+
+	if b := int8(sum); b != 0 {
+		return b
+	}
+	return 1
+}
+
+// signatureHash will hash the signature like Java's Arrays.hashCode.
+func signatureHash(s codecs.Signature) int32 {
+	h := int32(1)
+	for _, b := range s {
+		h = 31*h + int32(int8(b))
+	}
+
+	return h
+}
+
+// SignatureCache mirrors the signature cache of a client, so the signatures
+// of previous messages can be sent as an index into it.
+type SignatureCache struct {
+	entries [SignatureCacheSize]*codecs.Signature
+}
+
+// Pack will return the signature as sent to the client, as a reference to
+// the cache if it holds the signature.
+func (c *SignatureCache) Pack(signature codecs.Signature) packet.PackedSignature {
+	for i, entry := range c.entries {
+		if entry != nil && *entry == signature {
+			return packet.PackedSignature{ID: codecs.VarInt(i + 1)}
+		}
+	}
+
+	return packet.PackedSignature{Signature: signature}
+}
+
+// Push will update the cache the way the client does when it receives a
+// message with the last seen signatures and its own signature, which is nil
+// for unsigned messages.
+func (c *SignatureCache) Push(lastSeen []codecs.Signature, signature *codecs.Signature) {
+	queue := make([]codecs.Signature, 0, len(lastSeen)+1)
+	queue = append(queue, lastSeen...)
+	if signature != nil {
+		queue = append(queue, *signature)
+	}
+
+	pushed := make(map[codecs.Signature]bool, len(queue))
+	for _, s := range queue {
+		pushed[s] = true
+	}
+
+	for i := 0; len(queue) > 0 && i < len(c.entries); i++ {
+		old := c.entries[i]
+		next := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		c.entries[i] = &next
+
+		if old != nil && !pushed[*old] {
+			queue = append([]codecs.Signature{*old}, queue...)
+		}
+	}
+}
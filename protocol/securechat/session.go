@@ -0,0 +1,285 @@
+// Package securechat implements the signed chat introduced in 1.19: player
+// chat sessions, message signatures and the acknowledgements of the last
+// seen messages. It follows the session based protocol used since 1.19.3.
+//
+// A server keeps a Player for every connected client. Chat messages and
+// signed commands it receives are checked with Receive, and messages are sent
+// to each recipient with PlayerChat, SystemChat or DisguisedChat. A Player
+// which is not Secure accepts and sends unsigned messages only, like a server
+// with enforce-secure-profile disabled.
+package securechat
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"justanother.org/protocolhelper/chat"
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/packet"
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// Possible Errors.
+var (
+	ErrInvalidKey            = errors.New("securechat: invalid public key")
+	ErrInvalidKeySignature   = errors.New("securechat: public key is not signed by a trusted key")
+	ErrExpiredKey            = errors.New("securechat: public key has expired")
+	ErrNoSession             = errors.New("securechat: message sent without a chat session")
+	ErrMissingSignature      = errors.New("securechat: message is not signed")
+	ErrInvalidSignature      = errors.New("securechat: invalid message signature")
+	ErrOutOfOrder            = errors.New("securechat: message is older than the previous one")
+	ErrInvalidAcknowledgment = errors.New("securechat: invalid last seen acknowledgement")
+	ErrInvalidChecksum       = errors.New("securechat: last seen checksum does not match")
+)
+
+// Session is a chat session of a player, started with a session update.
+type Session struct {
+	ID  [16]byte
+	Key PublicKey
+}
+
+// Message is a chat message received from a player.
+type Message struct {
+	Sender [16]byte
+
+	// Index is the position of the message in the sender's session.
+	Index int32
+
+	Content   string
+	Timestamp time.Time
+	Salt      int64
+
+	// LastSeen are the signatures of the messages the sender had seen.
+	LastSeen []codecs.Signature
+
+	// Signature is nil for unsigned messages.
+	Signature *codecs.Signature
+}
+
+// Player holds the chat state of a connected player, both as the sender and
+// as a recipient of messages.
+type Player struct {
+	UUID     [16]byte
+	Protocol uint16
+
+	// Secure players have to start a session and sign their messages.
+	Secure bool
+
+	Session *Session
+
+	index         int32
+	lastTimestamp time.Time
+	globalIndex   int32
+
+	lastSeen *LastSeenValidator
+	cache    SignatureCache
+}
+
+// NewPlayer will create the chat state of a player connected with the protocol version.
+func NewPlayer(uuid [16]byte, protocol uint16, secure bool) *Player {
+	return &Player{
+		UUID:     uuid,
+		Protocol: protocol,
+		Secure:   secure,
+		lastSeen: NewLastSeenValidator(),
+	}
+}
+
+// UpdateSession will start the chat session sent by the player, after
+// checking its key against the trusted keys. Players which are not Secure
+// ignore sessions.
+func (p *Player) UpdateSession(s packet.PlayPlayerSession, trusted []*rsa.PublicKey) error {
+	if !p.Secure {
+		return nil
+	}
+
+	key, err := ParsePublicKey(s)
+	if err != nil {
+		return err
+	}
+	if key.Expired(time.Now()) {
+		return ErrExpiredKey
+	}
+	if err = key.Verify(p.UUID, trusted); err != nil {
+		return err
+	}
+
+	p.Session = &Session{ID: [16]byte(s.SessionID), Key: key}
+	p.index = 0
+	return nil
+}
+
+// Acknowledge will handle a message acknowledgement sent by the player.
+func (p *Player) Acknowledge(a packet.PlayMessageAcknowledgment) error {
+	return p.lastSeen.ApplyOffset(int(a.MessageCount))
+}
+
+// Receive will check a chat message sent by the player and apply its
+// acknowledgements. Secure players have to sign their messages, the signature
+// of other players is dropped.
+func (p *Player) Receive(m packet.PlaySendChatMessage) (*Message, error) {
+	lastSeen, err := p.lastSeen.ApplyUpdate(int(m.MessageCount), m.Acknowledged, int8(m.Checksum))
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		Sender:    p.UUID,
+		Content:   string(m.Message),
+		Timestamp: time.UnixMilli(int64(m.Timestamp)),
+		Salt:      int64(m.Salt),
+		LastSeen:  lastSeen,
+	}
+	if !p.Secure {
+		return msg, nil
+	}
+
+	if msg.Timestamp.Before(p.lastTimestamp) {
+		return nil, ErrOutOfOrder
+	}
+	p.lastTimestamp = msg.Timestamp
+
+	if p.Session == nil {
+		return nil, ErrNoSession
+	}
+	if !m.HasSignature {
+		return nil, ErrMissingSignature
+	}
+	if p.Session.Key.Expired(time.Now()) {
+		return nil, ErrExpiredKey
+	}
+
+	signature := codecs.Signature(m.Signature)
+	msg.Index = p.index
+	msg.Signature = &signature
+	if err = p.verify(msg); err != nil {
+		return nil, err
+	}
+
+	p.index++
+	return msg, nil
+}
+
+// ReceiveCommand will apply the acknowledgements of a signed chat command and
+// return the signatures of the messages the player had seen. The argument
+// signatures are not verified.
+func (p *Player) ReceiveCommand(c packet.PlaySignedChatCommand) ([]codecs.Signature, error) {
+	lastSeen, err := p.lastSeen.ApplyUpdate(int(c.MessageCount), c.Acknowledged, int8(c.Checksum))
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Secure {
+		timestamp := time.UnixMilli(int64(c.Timestamp))
+		if timestamp.Before(p.lastTimestamp) {
+			return nil, ErrOutOfOrder
+		}
+		p.lastTimestamp = timestamp
+	}
+
+	return lastSeen, nil
+}
+
+// verify will check the signature of a message against the session key.
+func (p *Player) verify(m *Message) error {
+	h := sha256.New()
+	for _, v := range []interface{}{
+		int32(1), p.UUID, p.Session.ID, m.Index,
+		m.Salt, m.Timestamp.Unix(), int32(len(m.Content)),
+	} {
+		binary.Write(h, binary.BigEndian, v)
+	}
+	h.Write([]byte(m.Content))
+
+	binary.Write(h, binary.BigEndian, int32(len(m.LastSeen)))
+	for _, s := range m.LastSeen {
+		h.Write(s[:])
+	}
+
+	if rsa.VerifyPKCS1v15(p.Session.Key.Key, crypto.SHA256, h.Sum(nil), m.Signature[:]) != nil {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// chatType will return the chat type as sent to the player.
+func (p *Player) chatType(id int32) codecs.VarInt {
+	if p.Protocol >= version.V1_20_5 {
+		return codecs.VarInt(id + 1)
+	}
+	return codecs.VarInt(id)
+}
+
+// PlayerChat will create the packet sending the message to this player, with
+// the chat type given by its registry ID and the decoration of the sender and
+// optional target names. The signature of the message is dropped for players
+// which are not Secure.
+func (p *Player) PlayerChat(m *Message, chatType int32, sender, target chat.Message) packet.PlayPlayerChatMessage {
+	signature := m.Signature
+	if !p.Secure {
+		signature = nil
+	}
+
+	pkt := packet.PlayPlayerChatMessage{
+		GlobalIndex: codecs.VarInt(p.globalIndex),
+		Sender:      codecs.UUID(m.Sender),
+		Index:       codecs.VarInt(m.Index),
+		Message:     codecs.String(m.Content),
+		Timestamp:   codecs.Long(m.Timestamp.UnixMilli()),
+		Salt:        codecs.Long(m.Salt),
+		FilterType:  packet.FilterPassThrough,
+		ChatType:    p.chatType(chatType),
+		SenderName:  codecs.Chat{Message: sender},
+	}
+	p.globalIndex++
+
+	if signature != nil {
+		pkt.HasSignature = true
+		pkt.Signature = *signature
+
+		pkt.PreviousMessages = make([]packet.PackedSignature, 0, len(m.LastSeen))
+		for _, s := range m.LastSeen {
+			pkt.PreviousMessages = append(pkt.PreviousMessages, p.cache.Pack(s))
+		}
+
+		p.cache.Push(m.LastSeen, signature)
+		p.lastSeen.AddPending(*signature)
+	}
+
+	if target != nil {
+		pkt.HasTargetName = true
+		pkt.TargetName = codecs.Chat{Message: target}
+	}
+
+	return pkt
+}
+
+// DisguisedChat will create the packet sending unsigned content to this
+// player, decorated like a player message.
+func (p *Player) DisguisedChat(content chat.Message, chatType int32, sender, target chat.Message) packet.PlayDisguisedChatMessage {
+	pkt := packet.PlayDisguisedChatMessage{
+		Message:    codecs.Chat{Message: content},
+		ChatType:   p.chatType(chatType),
+		SenderName: codecs.Chat{Message: sender},
+	}
+	if target != nil {
+		pkt.HasTargetName = true
+		pkt.TargetName = codecs.Chat{Message: target}
+	}
+
+	return pkt
+}
+
+// SystemChat will create the packet sending a system message to this player,
+// shown above the hotbar if overlay is set.
+func (p *Player) SystemChat(content chat.Message, overlay bool) packet.PlaySystemChatMessage {
+	return packet.PlaySystemChatMessage{
+		Content: codecs.Chat{Message: content},
+		Overlay: codecs.Boolean(overlay),
+	}
+}
@@ -0,0 +1,36 @@
+// Package version lists the protocol version numbers at which the wire format
+// of a type changed, so codecs and packets can select the right layout.
+package version
+
+// Protocol version numbers of the releases the library knows about.
+const (
+	V1_8    = 47
+	V1_9    = 107
+	V1_9_4  = 110
+	V1_12   = 335
+	V1_12_2 = 340
+	V1_13   = 393
+	V1_13_2 = 404
+	V1_14   = 477
+	V1_15   = 573
+	V1_16   = 735
+	V1_16_2 = 751
+	V1_17   = 755
+	V1_18   = 757
+	V1_19   = 759
+	V1_19_1 = 760
+	V1_19_3 = 761
+	V1_19_4 = 762
+	V1_20   = 763
+	V1_20_2 = 764
+	V1_20_3 = 765
+	V1_20_5 = 766
+	V1_21   = 767
+	V1_21_2 = 768
+	V1_21_4 = 769
+	V1_21_5 = 770
+
+	// Latest is the newest protocol version the library supports, it is used
+	// when a versioned codec is encoded or decoded without a connection.
+	Latest = V1_21_5
+)
@@ -0,0 +1,222 @@
+package registries
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/packet"
+)
+
+// LoadDataPack will add the entries of the Synced registries found in a data
+// pack, the directory holding the "data" directory, such as an extracted
+// server jar. Entries are read from data/<namespace>/<registry>/<name>.json
+// and added sorted by name, the order the vanilla server uses.
+func (s *Set) LoadDataPack(dir string, pack *packet.KnownPack) error {
+	namespaces, err := os.ReadDir(filepath.Join(dir, "data"))
+	if err != nil {
+		return err
+	}
+
+	for _, registry := range Synced {
+		path := registry[strings.Index(registry, ":")+1:]
+
+		var entries []Entry
+		for _, ns := range namespaces {
+			if !ns.IsDir() {
+				continue
+			}
+
+			root := filepath.Join(dir, "data", ns.Name(), filepath.FromSlash(path))
+			files, err := os.ReadDir(root)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			for _, f := range files {
+				if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+					continue
+				}
+
+				data, err := loadJSON(filepath.Join(root, f.Name()))
+				if err != nil {
+					return err
+				}
+				c, ok := data.(nbt.Compound)
+				if !ok {
+					return ErrInvalidCodec
+				}
+
+				name := ns.Name() + ":" + strings.TrimSuffix(f.Name(), ".json")
+				entries = append(entries, Entry{Name: name, Data: c, Pack: pack})
+			}
+		}
+
+		if len(entries) == 0 {
+			continue
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Name < entries[j].Name
+		})
+		r := s.Registry(registry)
+		for _, e := range entries {
+			r.Add(e)
+		}
+	}
+
+	return nil
+}
+
+// LoadCodec will add the registries of a registry codec file, either a JSON
+// file or an NBT file, which may be gzip compressed.
+func (s *Set) LoadCodec(path string, pack *packet.KnownPack) error {
+	var (
+		data interface{}
+		err  error
+	)
+	if strings.HasSuffix(path, ".json") {
+		data, err = loadJSON(path)
+	} else {
+		data, err = loadNBT(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	codec, ok := data.(nbt.Compound)
+	if !ok {
+		return ErrInvalidCodec
+	}
+
+	return s.ReadCodec(codec, pack)
+}
+
+func loadJSON(path string) (interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.UseNumber()
+
+	var v interface{}
+	if err = dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return FromJSON(v), nil
+}
+
+func loadNBT(path string) (interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, _ := r.(*bufio.Reader).Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if r, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	}
+
+	_, tag, err := nbt.Read(r)
+	return tag, err
+}
+
+// FromJSON will turn a value decoded from JSON, with numbers decoded as
+// json.Number, into an NBT tag. Whole numbers become TAG_Int or TAG_Long,
+// other numbers TAG_Double and booleans TAG_Byte, which is how the vanilla
+// client reads them. Lists mixing types hold doubles if they only hold
+// numbers, and otherwise wrap their elements in compounds with an empty key.
+func FromJSON(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		c := make(nbt.Compound, len(val))
+		for k, item := range val {
+			if tag := FromJSON(item); tag != nil {
+				c[k] = tag
+			}
+		}
+		return c
+	case []interface{}:
+		return listFromJSON(val)
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			if i == int64(int32(i)) {
+				return int32(i)
+			}
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case float64:
+		return val
+	case bool:
+		if val {
+			return int8(1)
+		}
+		return int8(0)
+	case string:
+		return val
+	}
+
+	return nil
+}
+
+func listFromJSON(val []interface{}) nbt.List {
+	list := make(nbt.List, 0, len(val))
+	mixed, numeric := false, true
+	for _, item := range val {
+		tag := FromJSON(item)
+		if tag == nil {
+			continue
+		}
+
+		if len(list) > 0 {
+			a, _ := nbt.TypeOf(list[0])
+			b, _ := nbt.TypeOf(tag)
+			mixed = mixed || a != b
+		}
+		switch tag.(type) {
+		case int8, int32, int64, float64:
+		default:
+			numeric = false
+		}
+
+		list = append(list, tag)
+	}
+	if !mixed {
+		return list
+	}
+
+	for i, tag := range list {
+		switch t := tag.(type) {
+		case int8:
+			list[i] = float64(t)
+		case int32:
+			list[i] = float64(t)
+		case int64:
+			list[i] = float64(t)
+		}
+		if _, ok := list[i].(nbt.Compound); !ok && !numeric {
+			list[i] = nbt.Compound{"": tag}
+		}
+	}
+
+	return list
+}
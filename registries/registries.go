@@ -0,0 +1,325 @@
+// Package registries holds the data driven registries a server sends to its
+// clients, such as dimension types, biomes, chat types and damage types.
+//
+// Vanilla entries are loaded from the data packs of a server jar, or from a
+// registry codec dump, and applications add their own entries on top. The
+// registries are sent in the Join Game packet before 1.20.2, as a single
+// Registry Data packet until 1.20.5, and as one Registry Data packet per
+// registry after.
+package registries
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/packet"
+	"justanother.org/protocolhelper/protocol/version"
+)
+
+// ErrInvalidCodec is returned for registry codecs which are not in the vanilla layout.
+var ErrInvalidCodec = errors.New("registries: invalid registry codec")
+
+// Synced are the registries clients are sent, in the paths they have in data packs.
+var Synced = []string{
+	"minecraft:banner_pattern",
+	"minecraft:cat_variant",
+	"minecraft:chat_type",
+	"minecraft:chicken_variant",
+	"minecraft:cow_variant",
+	"minecraft:damage_type",
+	"minecraft:dimension_type",
+	"minecraft:enchantment",
+	"minecraft:frog_variant",
+	"minecraft:instrument",
+	"minecraft:jukebox_song",
+	"minecraft:painting_variant",
+	"minecraft:pig_variant",
+	"minecraft:trim_material",
+	"minecraft:trim_pattern",
+	"minecraft:wolf_sound_variant",
+	"minecraft:wolf_variant",
+	"minecraft:worldgen/biome",
+}
+
+// syncedSince is the protocol version each of the Synced registries is sent from.
+var syncedSince = map[string]uint16{
+	"minecraft:banner_pattern":     version.V1_20_5,
+	"minecraft:cat_variant":        version.V1_21_5,
+	"minecraft:chat_type":          version.V1_19,
+	"minecraft:chicken_variant":    version.V1_21_5,
+	"minecraft:cow_variant":        version.V1_21_5,
+	"minecraft:damage_type":        version.V1_19_4,
+	"minecraft:dimension_type":     version.V1_16_2,
+	"minecraft:enchantment":        version.V1_21,
+	"minecraft:frog_variant":       version.V1_21_5,
+	"minecraft:instrument":         version.V1_21_2,
+	"minecraft:jukebox_song":       version.V1_21,
+	"minecraft:painting_variant":   version.V1_21,
+	"minecraft:pig_variant":        version.V1_21_5,
+	"minecraft:trim_material":      version.V1_19_4,
+	"minecraft:trim_pattern":       version.V1_19_4,
+	"minecraft:wolf_sound_variant": version.V1_21_5,
+	"minecraft:wolf_variant":       version.V1_20_5,
+	"minecraft:worldgen/biome":     version.V1_16_2,
+}
+
+// IsSynced will report whether clients of the protocol version are sent the
+// registry. Registries other than the Synced ones are always sent.
+func IsSynced(name string, protocol uint16) bool {
+	since, ok := syncedSince[Namespaced(name)]
+	return !ok || protocol >= since
+}
+
+// Namespaced will add the minecraft namespace to names without one.
+func Namespaced(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+
+	return "minecraft:" + name
+}
+
+// Entry is an entry of a registry.
+type Entry struct {
+	Name string
+	Data nbt.Compound
+
+	// Pack is the known pack the entry was loaded from, nil for entries
+	// added by the application. Clients knowing the pack are sent the entry
+	// without its data since 1.20.5.
+	Pack *packet.KnownPack
+}
+
+// Registry is a list of entries, their IDs given by their order.
+type Registry struct {
+	Name string
+
+	entries []*Entry
+	index   map[string]int
+}
+
+// NewRegistry will create an empty registry.
+func NewRegistry(name string) *Registry {
+	return &Registry{Name: Namespaced(name), index: make(map[string]int)}
+}
+
+// Add will add the entry and return its ID. An entry replacing one of the
+// same name keeps the ID of the replaced entry.
+func (r *Registry) Add(e Entry) int {
+	e.Name = Namespaced(e.Name)
+	if id, ok := r.index[e.Name]; ok {
+		r.entries[id] = &e
+		return id
+	}
+
+	r.index[e.Name] = len(r.entries)
+	r.entries = append(r.entries, &e)
+	return len(r.entries) - 1
+}
+
+// Get will return the entry of the name.
+func (r *Registry) Get(name string) (*Entry, bool) {
+	id, ok := r.index[Namespaced(name)]
+	if !ok {
+		return nil, false
+	}
+
+	return r.entries[id], true
+}
+
+// ID will return the ID of the entry of the name.
+func (r *Registry) ID(name string) (int, bool) {
+	id, ok := r.index[Namespaced(name)]
+	return id, ok
+}
+
+// Entries will return the entries ordered by their ID.
+func (r *Registry) Entries() []*Entry {
+	return append([]*Entry(nil), r.entries...)
+}
+
+// Len will return the number of entries.
+func (r *Registry) Len() int {
+	return len(r.entries)
+}
+
+// Set is the set of registries sent to clients.
+type Set struct {
+	registries map[string]*Registry
+	order      []string
+}
+
+// NewSet will create an empty set of registries.
+func NewSet() *Set {
+	return &Set{registries: make(map[string]*Registry)}
+}
+
+// Registry will return the registry of the name, creating it if needed.
+func (s *Set) Registry(name string) *Registry {
+	name = Namespaced(name)
+	if r, ok := s.registries[name]; ok {
+		return r
+	}
+
+	r := NewRegistry(name)
+	s.registries[name] = r
+	s.order = append(s.order, name)
+	return r
+}
+
+// Lookup will return the registry of the name, if it exists.
+func (s *Set) Lookup(name string) (*Registry, bool) {
+	r, ok := s.registries[Namespaced(name)]
+	return r, ok
+}
+
+// Names will return the names of the registries in the order they were created.
+func (s *Set) Names() []string {
+	return append([]string(nil), s.order...)
+}
+
+// Add will add a custom entry to the registry and return its ID.
+func (s *Set) Add(registry, name string, data nbt.Compound) int {
+	return s.Registry(registry).Add(Entry{Name: name, Data: data})
+}
+
+// Codec will return the registry codec, the compound holding the registries
+// the protocol version syncs, as sent in the Join Game packet from 1.16.2 and
+// in the Registry Data packet of 1.20.2 to 1.20.4.
+func (s *Set) Codec(protocol uint16) nbt.Compound {
+	codec := make(nbt.Compound, len(s.order))
+	for _, name := range s.order {
+		if !IsSynced(name, protocol) {
+			continue
+		}
+		r := s.registries[name]
+
+		value := make(nbt.List, 0, len(r.entries))
+		for id, e := range r.entries {
+			value = append(value, nbt.Compound{
+				"name":    e.Name,
+				"id":      int32(id),
+				"element": e.Data,
+			})
+		}
+
+		codec[name] = nbt.Compound{"type": name, "value": value}
+	}
+
+	return codec
+}
+
+// ReadCodec will add the registries of a registry codec to the set, in the
+// order of their names.
+func (s *Set) ReadCodec(codec nbt.Compound, pack *packet.KnownPack) error {
+	names := make([]string, 0, len(codec))
+	for name := range codec {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		c, ok := codec[name].(nbt.Compound)
+		if !ok {
+			return ErrInvalidCodec
+		}
+		list, ok := c.List("value")
+		if !ok {
+			return ErrInvalidCodec
+		}
+
+		// Entries are added by their id, which need not follow the list order.
+		entries := make([]*Entry, len(list))
+		for _, item := range list {
+			ec, ok := item.(nbt.Compound)
+			if !ok {
+				return ErrInvalidCodec
+			}
+			entryName, _ := ec.String("name")
+			id, ok := ec.Number("id")
+			data, _ := ec.Compound("element")
+			if entryName == "" || !ok || id < 0 || int(id) >= len(entries) || entries[id] != nil {
+				return ErrInvalidCodec
+			}
+
+			entries[id] = &Entry{Name: entryName, Data: data, Pack: pack}
+		}
+
+		r := s.Registry(name)
+		for _, e := range entries {
+			r.Add(*e)
+		}
+	}
+
+	return nil
+}
+
+// DimensionType will return the element of a dimension type, as sent in the
+// Join Game packet of 1.16.2 to 1.18.2.
+func (s *Set) DimensionType(name string) (nbt.Compound, bool) {
+	r, ok := s.Lookup("dimension_type")
+	if !ok {
+		return nil, false
+	}
+	e, ok := r.Get(name)
+	if !ok {
+		return nil, false
+	}
+
+	return e.Data, true
+}
+
+// Packets will return the Registry Data packets of the registries the
+// protocol version syncs, leaving out the data of entries from the packs the
+// client knows. Before 1.20.2 no packets are sent, the Codec is part of the
+// Join Game packet.
+func (s *Set) Packets(protocol uint16, known []packet.KnownPack) []packet.Holder {
+	switch {
+	case protocol < version.V1_20_2:
+		return nil
+	case protocol < version.V1_20_5:
+		return []packet.Holder{packet.ConfigRegistryCodec{Codec: codecs.NBT{V: s.Codec(protocol)}}}
+	}
+
+	packets := make([]packet.Holder, 0, len(s.order))
+	for _, name := range s.order {
+		if !IsSynced(name, protocol) {
+			continue
+		}
+		r := s.registries[name]
+
+		p := packet.ConfigRegistryData{
+			RegistryID: codecs.String(name),
+			Entries:    make([]packet.RegistryEntry, 0, len(r.entries)),
+		}
+		for _, e := range r.entries {
+			entry := packet.RegistryEntry{ID: codecs.String(e.Name)}
+			if !isKnown(e.Pack, known) {
+				entry.HasData = true
+				entry.Data = codecs.NBT{V: e.Data}
+			}
+			p.Entries = append(p.Entries, entry)
+		}
+
+		packets = append(packets, p)
+	}
+
+	return packets
+}
+
+func isKnown(pack *packet.KnownPack, known []packet.KnownPack) bool {
+	if pack == nil {
+		return false
+	}
+
+	for _, k := range known {
+		if k == *pack {
+			return true
+		}
+	}
+
+	return false
+}
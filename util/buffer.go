@@ -0,0 +1,318 @@
+package util
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+)
+
+// Buffer is a byte slice values are appended to with the Put methods and read
+// from with the Get methods, without allocating once it has grown large
+// enough. It is also an io.Reader and io.Writer, and the read and write
+// functions of this package use the Put and Get methods when given a *Buffer.
+type Buffer struct {
+	data []byte
+	off  int
+}
+
+// NewBuffer will create a buffer reading from data.
+func NewBuffer(data []byte) *Buffer {
+	return &Buffer{data: data}
+}
+
+// Bytes will return the unread part of the buffer.
+func (b *Buffer) Bytes() []byte {
+	return b.data[b.off:]
+}
+
+// Len will return the number of unread bytes.
+func (b *Buffer) Len() int {
+	return len(b.data) - b.off
+}
+
+// Reset will empty the buffer, keeping its memory.
+func (b *Buffer) Reset() {
+	b.data = b.data[:0]
+	b.off = 0
+}
+
+// Grow will make room for n more bytes and return them, to be filled by the caller.
+func (b *Buffer) Grow(n int) []byte {
+	l := len(b.data)
+	if cap(b.data)-l < n {
+		data := make([]byte, l, 2*cap(b.data)+n)
+		copy(data, b.data)
+		b.data = data
+	}
+
+	b.data = b.data[:l+n]
+	return b.data[l:]
+}
+
+// Write will append p to the buffer.
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.PutBytes(p)
+	return len(p), nil
+}
+
+// WriteByte will append c to the buffer.
+func (b *Buffer) WriteByte(c byte) error {
+	b.PutUint8(c)
+	return nil
+}
+
+// Read will read the next len(p) unread bytes, or as many as there are.
+func (b *Buffer) Read(p []byte) (int, error) {
+	if b.Len() == 0 {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+
+	n := copy(p, b.data[b.off:])
+	b.off += n
+	return n, nil
+}
+
+// ReadByte will read the next byte.
+func (b *Buffer) ReadByte() (byte, error) {
+	return b.GetUint8()
+}
+
+// next will return the next n unread bytes.
+func (b *Buffer) next(n int) ([]byte, error) {
+	if b.Len() < n {
+		if b.Len() == 0 {
+			return nil, io.EOF
+		}
+		return nil, ErrTruncated
+	}
+
+	p := b.data[b.off : b.off+n]
+	b.off += n
+	return p, nil
+}
+
+// PutBytes will append the bytes.
+func (b *Buffer) PutBytes(p []byte) {
+	b.data = append(b.data, p...)
+}
+
+// PutBool will append the bool.
+func (b *Buffer) PutBool(val bool) {
+	if val {
+		b.PutUint8(1)
+	} else {
+		b.PutUint8(0)
+	}
+}
+
+// PutInt8 will append the int8.
+func (b *Buffer) PutInt8(val int8) {
+	b.PutUint8(uint8(val))
+}
+
+// PutUint8 will append the uint8.
+func (b *Buffer) PutUint8(val uint8) {
+	b.data = append(b.data, val)
+}
+
+// PutInt16 will append the int16.
+func (b *Buffer) PutInt16(val int16) {
+	b.PutUint16(uint16(val))
+}
+
+// PutUint16 will append the uint16.
+func (b *Buffer) PutUint16(val uint16) {
+	binary.BigEndian.PutUint16(b.Grow(2), val)
+}
+
+// PutInt32 will append the int32.
+func (b *Buffer) PutInt32(val int32) {
+	b.PutUint32(uint32(val))
+}
+
+// PutUint32 will append the uint32.
+func (b *Buffer) PutUint32(val uint32) {
+	binary.BigEndian.PutUint32(b.Grow(4), val)
+}
+
+// PutInt64 will append the int64.
+func (b *Buffer) PutInt64(val int64) {
+	b.PutUint64(uint64(val))
+}
+
+// PutUint64 will append the uint64.
+func (b *Buffer) PutUint64(val uint64) {
+	binary.BigEndian.PutUint64(b.Grow(8), val)
+}
+
+// PutFloat32 will append the float32.
+func (b *Buffer) PutFloat32(val float32) {
+	b.PutUint32(math.Float32bits(val))
+}
+
+// PutFloat64 will append the float64.
+func (b *Buffer) PutFloat64(val float64) {
+	b.PutUint64(math.Float64bits(val))
+}
+
+// PutVarInt will append the int as a VarInt.
+func (b *Buffer) PutVarInt(val int) {
+	uval := uint32(val)
+	for uval >= 0x80 {
+		b.data = append(b.data, byte(uval)|0x80)
+		uval >>= 7
+	}
+	b.data = append(b.data, byte(uval))
+}
+
+// PutVarLong will append the int64 as a VarLong.
+func (b *Buffer) PutVarLong(val int64) {
+	uval := uint64(val)
+	for uval >= 0x80 {
+		b.data = append(b.data, byte(uval)|0x80)
+		uval >>= 7
+	}
+	b.data = append(b.data, byte(uval))
+}
+
+// PutString will append the string with its VarInt length.
+func (b *Buffer) PutString(val string) {
+	b.PutVarInt(len(val))
+	b.data = append(b.data, val...)
+}
+
+// GetBytes will read the next n bytes. They share the memory of the buffer.
+func (b *Buffer) GetBytes(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, ErrInvalidLength
+	}
+
+	return b.next(n)
+}
+
+// GetBool will read a bool.
+func (b *Buffer) GetBool() (bool, error) {
+	val, err := b.GetUint8()
+	return val != 0, err
+}
+
+// GetInt8 will read an int8.
+func (b *Buffer) GetInt8() (int8, error) {
+	val, err := b.GetUint8()
+	return int8(val), err
+}
+
+// GetUint8 will read an uint8.
+func (b *Buffer) GetUint8() (uint8, error) {
+	if b.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	val := b.data[b.off]
+	b.off++
+	return val, nil
+}
+
+// GetInt16 will read an int16.
+func (b *Buffer) GetInt16() (int16, error) {
+	val, err := b.GetUint16()
+	return int16(val), err
+}
+
+// GetUint16 will read an uint16.
+func (b *Buffer) GetUint16() (uint16, error) {
+	p, err := b.next(2)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint16(p), nil
+}
+
+// GetInt32 will read an int32.
+func (b *Buffer) GetInt32() (int32, error) {
+	val, err := b.GetUint32()
+	return int32(val), err
+}
+
+// GetUint32 will read an uint32.
+func (b *Buffer) GetUint32() (uint32, error) {
+	p, err := b.next(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(p), nil
+}
+
+// GetInt64 will read an int64.
+func (b *Buffer) GetInt64() (int64, error) {
+	val, err := b.GetUint64()
+	return int64(val), err
+}
+
+// GetUint64 will read an uint64.
+func (b *Buffer) GetUint64() (uint64, error) {
+	p, err := b.next(8)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint64(p), nil
+}
+
+// GetFloat32 will read a float32.
+func (b *Buffer) GetFloat32() (float32, error) {
+	val, err := b.GetUint32()
+	return math.Float32frombits(val), err
+}
+
+// GetFloat64 will read a float64.
+func (b *Buffer) GetFloat64() (float64, error) {
+	val, err := b.GetUint64()
+	return math.Float64frombits(val), err
+}
+
+// GetVarInt will read a VarInt.
+func (b *Buffer) GetVarInt() (int, error) {
+	return ReadVarInt(b)
+}
+
+// GetVarLong will read a VarLong.
+func (b *Buffer) GetVarLong() (int64, error) {
+	return ReadVarLong(b)
+}
+
+// GetString will read a string with its VarInt length.
+func (b *Buffer) GetString() (string, error) {
+	return ReadString(b)
+}
+
+// maxPooledBuffer is the capacity above which buffers are not kept in the
+// pool, so a single large packet does not hold on to its memory.
+const maxPooledBuffer = 64 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &Buffer{data: make([]byte, 0, 512)}
+	},
+}
+
+// AcquireBuffer will return an empty buffer from the pool.
+func AcquireBuffer() *Buffer {
+	return bufferPool.Get().(*Buffer)
+}
+
+// ReleaseBuffer will return the buffer to the pool. It may not be used afterwards.
+func ReleaseBuffer(b *Buffer) {
+	if cap(b.data) > maxPooledBuffer {
+		return
+	}
+
+	b.Reset()
+	bufferPool.Put(b)
+}
@@ -8,54 +8,155 @@ import (
 	"math"
 )
 
-// ReadString will read a string from the reader.
+// All read functions return io.EOF if the reader ends before the first byte of
+// the value and ErrTruncated if it ends in the middle of it. Readers which
+// implement io.ByteReader, such as *bytes.Buffer and *bufio.Reader, are read
+// byte by byte without allocating, and a *Buffer is read with its Get methods.
+
+// Possible Errors.
+var (
+	// ErrTruncated is returned when the input ends in the middle of a value.
+	ErrTruncated = fmt.Errorf("util: truncated value: %w", io.ErrUnexpectedEOF)
+	// ErrOverflow is returned when a VarInt or VarLong is longer than its type allows.
+	ErrOverflow = errors.New("util: variable length number overflows")
+	// ErrInvalidLength is returned when a length prefix is negative or above its maximum.
+	ErrInvalidLength = errors.New("util: invalid length")
+	// ErrStringTooLong is returned for strings longer than their maximum.
+	ErrStringTooLong = errors.New("util: string is longer than its maximum")
+)
+
+// MaxStringLength is the maximum length of strings without a declared
+// maximum, in UTF-16 code units.
+const MaxStringLength = 32767
+
+// UTF16Len will return the length of s in UTF-16 code units, which is how the
+// vanilla client measures strings.
+func UTF16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+
+	return n
+}
+
+// readFull will fill buf, turning a partial read into ErrTruncated.
+func readFull(reader io.Reader, buf []byte) error {
+	_, err := io.ReadFull(reader, buf)
+	if err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+
+	return err
+}
+
+// ReadString will read a string of at most MaxStringLength characters from the reader.
 func ReadString(reader io.Reader) (val string, err error) {
+	return ReadStringMax(reader, MaxStringLength)
+}
+
+// ReadStringMax will read a string of at most max UTF-16 code units from the
+// reader. Lengths which cannot fit, at three bytes per code unit, are rejected
+// before reading the string.
+func ReadStringMax(reader io.Reader, max int) (val string, err error) {
 	length, err := ReadVarInt(reader)
 	if err != nil {
 		return
 	}
 	if length < 0 {
-		err = fmt.Errorf("Decode, String length is below zero: %d", length)
+		err = ErrInvalidLength
 		return
 	}
-	if length > 1048576 { // 2^(21-1)
-		err = fmt.Errorf("Decode, String length is above maximum: %d", length)
+	if length > max*3 {
+		err = ErrStringTooLong
 		return
 	}
-	bytes := make([]byte, length)
-	_, err = reader.Read(bytes)
+
+	var bytes []byte
+	if b, ok := reader.(*Buffer); ok {
+		bytes, err = b.GetBytes(length)
+	} else {
+		bytes = make([]byte, length)
+		err = readFull(reader, bytes)
+	}
+	if err == io.EOF {
+		err = ErrTruncated
+	}
 	if err != nil {
 		return
 	}
+
 	val = string(bytes)
+	if UTF16Len(val) > max {
+		val, err = "", ErrStringTooLong
+	}
 	return
 }
 
 // ReadVarInt will read an int from the reader.
 func ReadVarInt(reader io.Reader) (result int, err error) {
-	var bytes byte
-	var b byte
+	var value uint32
 
-	for {
-		b, err = ReadUint8(reader)
-		if err != nil {
+	for i := 0; ; i++ {
+		if i == 5 {
+			err = ErrOverflow
 			return
 		}
-		result |= int(uint(b&0x7F) << uint(bytes*7))
-		bytes++
-		if bytes > 5 {
-			err = errors.New("Decode, VarInt is too long")
+
+		var b byte
+		if b, err = readVarByte(reader, i); err != nil {
 			return
 		}
-		if (b & 0x80) == 0x80 {
-			continue
+
+		value |= uint32(b&0x7F) << uint(i*7)
+		if b&0x80 == 0 {
+			break
 		}
-		break
 	}
 
+	result = int(int32(value))
 	return
 }
 
+// ReadVarLong will read an int64 from the reader.
+func ReadVarLong(reader io.Reader) (result int64, err error) {
+	var value uint64
+
+	for i := 0; ; i++ {
+		if i == 10 {
+			err = ErrOverflow
+			return
+		}
+
+		var b byte
+		if b, err = readVarByte(reader, i); err != nil {
+			return
+		}
+
+		value |= uint64(b&0x7F) << uint(i*7)
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	result = int64(value)
+	return
+}
+
+// readVarByte will read byte i of a variable length number.
+func readVarByte(reader io.Reader, i int) (byte, error) {
+	b, err := ReadUint8(reader)
+	if err == io.EOF && i > 0 {
+		err = ErrTruncated
+	}
+
+	return b, err
+}
+
 // ReadBool will read a bool from the reader.
 func ReadBool(reader io.Reader) (val bool, err error) {
 	uval, err := ReadUint8(reader)
@@ -75,8 +176,12 @@ func ReadInt8(reader io.Reader) (val int8, err error) {
 
 // ReadUint8 will read an uint8 from the reader.
 func ReadUint8(reader io.Reader) (val uint8, err error) {
+	if br, ok := reader.(io.ByteReader); ok {
+		return br.ReadByte()
+	}
+
 	var protocol [1]byte
-	_, err = reader.Read(protocol[:1])
+	err = readFull(reader, protocol[:])
 	val = protocol[0]
 	return
 }
@@ -90,9 +195,15 @@ func ReadInt16(reader io.Reader) (val int16, err error) {
 
 // ReadUint16 will read an uint16 from the reader.
 func ReadUint16(reader io.Reader) (val uint16, err error) {
+	if b, ok := reader.(*Buffer); ok {
+		return b.GetUint16()
+	}
+
 	var protocol [2]byte
-	_, err = reader.Read(protocol[:2])
-	val = binary.BigEndian.Uint16(protocol[:2])
+	if err = readFull(reader, protocol[:]); err != nil {
+		return
+	}
+	val = binary.BigEndian.Uint16(protocol[:])
 	return
 }
 
@@ -105,9 +216,15 @@ func ReadInt32(reader io.Reader) (val int32, err error) {
 
 // ReadUint32 will read an uint32 from the reader.
 func ReadUint32(reader io.Reader) (val uint32, err error) {
+	if b, ok := reader.(*Buffer); ok {
+		return b.GetUint32()
+	}
+
 	var protocol [4]byte
-	_, err = reader.Read(protocol[:4])
-	val = binary.BigEndian.Uint32(protocol[:4])
+	if err = readFull(reader, protocol[:]); err != nil {
+		return
+	}
+	val = binary.BigEndian.Uint32(protocol[:])
 	return
 }
 
@@ -120,9 +237,15 @@ func ReadInt64(reader io.Reader) (val int64, err error) {
 
 // ReadUint64 will read an uint64 from the reader.
 func ReadUint64(reader io.Reader) (val uint64, err error) {
+	if b, ok := reader.(*Buffer); ok {
+		return b.GetUint64()
+	}
+
 	var protocol [8]byte
-	_, err = reader.Read(protocol[:8])
-	val = binary.BigEndian.Uint64(protocol[:8])
+	if err = readFull(reader, protocol[:]); err != nil {
+		return
+	}
+	val = binary.BigEndian.Uint64(protocol[:])
 	return
 }
 
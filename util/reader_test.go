@@ -0,0 +1,360 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"testing/iotest"
+)
+
+// readers will return the kinds of readers the read functions handle
+// differently, a *Buffer, an io.ByteReader and a plain io.Reader, all over
+// data.
+func readers(data []byte) map[string]io.Reader {
+	return map[string]io.Reader{
+		"Buffer":     NewBuffer(data),
+		"ByteReader": bytes.NewReader(data),
+		"Reader":     iotest.OneByteReader(bytes.NewReader(data)),
+	}
+}
+
+// checkTruncated will read every prefix of the encoded value and check the
+// error, io.EOF for no input and ErrTruncated for part of the value.
+func checkTruncated(t *testing.T, encoded []byte, read func(io.Reader) error) {
+	t.Helper()
+
+	for n := 0; n < len(encoded); n++ {
+		want := ErrTruncated
+		if n == 0 {
+			want = io.EOF
+		}
+
+		for name, r := range readers(encoded[:n]) {
+			if err := read(r); err != want {
+				t.Fatalf("%s: reading %d of %d bytes: got error %v, want %v", name, n, len(encoded), err, want)
+			}
+		}
+	}
+}
+
+// checkVarError will check the error of reading a variable length number
+// from arbitrary input.
+func checkVarError(t *testing.T, name string, err error) {
+	t.Helper()
+
+	if err != nil && err != io.EOF && err != ErrTruncated && err != ErrOverflow {
+		t.Fatalf("%s: unexpected error %v", name, err)
+	}
+}
+
+func FuzzReadVarInt(f *testing.F) {
+	for _, seed := range [][]byte{
+		{0x00},
+		{0x7f},
+		{0x80, 0x01},
+		{0xff, 0xff, 0xff, 0xff, 0x07},
+		{0xff, 0xff, 0xff, 0xff, 0x0f},
+		{0x80, 0x80, 0x80, 0x80, 0x80, 0x01},
+		{0x80},
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, r := range readers(data) {
+			val, err := ReadVarInt(r)
+			checkVarError(t, name, err)
+			if err != nil {
+				continue
+			}
+
+			// Numbers may be written with more bytes than needed, so the
+			// value is compared rather than the bytes.
+			var buf bytes.Buffer
+			if err = WriteVarInt(&buf, val); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadVarInt(&buf)
+			if err != nil || got != val {
+				t.Fatalf("%s: %d read back as %d, %v", name, val, got, err)
+			}
+		}
+
+		// Six bytes which all continue overflow.
+		overflow := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x00}
+		for name, r := range readers(overflow) {
+			if _, err := ReadVarInt(r); err != ErrOverflow {
+				t.Fatalf("%s: got error %v, want ErrOverflow", name, err)
+			}
+		}
+	})
+}
+
+func FuzzWriteVarInt(f *testing.F) {
+	for _, seed := range []int32{0, 1, 127, 128, 255, 2097151, math.MaxInt32, -1, math.MinInt32} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, val int32) {
+		var buf bytes.Buffer
+		if err := WriteVarInt(&buf, int(val)); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() > 5 {
+			t.Fatalf("%d written in %d bytes", val, buf.Len())
+		}
+
+		for name, r := range readers(buf.Bytes()) {
+			got, err := ReadVarInt(r)
+			if err != nil || got != int(val) {
+				t.Fatalf("%s: %d read back as %d, %v", name, val, got, err)
+			}
+		}
+
+		checkTruncated(t, buf.Bytes(), func(r io.Reader) error {
+			_, err := ReadVarInt(r)
+			return err
+		})
+	})
+}
+
+func FuzzReadVarLong(f *testing.F) {
+	for _, seed := range [][]byte{
+		{0x00},
+		{0x80, 0x01},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f},
+		{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01},
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for name, r := range readers(data) {
+			val, err := ReadVarLong(r)
+			checkVarError(t, name, err)
+			if err != nil {
+				continue
+			}
+
+			var buf bytes.Buffer
+			if err = WriteVarLong(&buf, val); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadVarLong(&buf)
+			if err != nil || got != val {
+				t.Fatalf("%s: %d read back as %d, %v", name, val, got, err)
+			}
+		}
+	})
+}
+
+func FuzzWriteVarLong(f *testing.F) {
+	for _, seed := range []int64{0, 1, 127, 128, math.MaxInt32, math.MaxInt64, -1, math.MinInt64} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, val int64) {
+		var buf bytes.Buffer
+		if err := WriteVarLong(&buf, val); err != nil {
+			t.Fatal(err)
+		}
+		if buf.Len() > 10 {
+			t.Fatalf("%d written in %d bytes", val, buf.Len())
+		}
+
+		for name, r := range readers(buf.Bytes()) {
+			got, err := ReadVarLong(r)
+			if err != nil || got != val {
+				t.Fatalf("%s: %d read back as %d, %v", name, val, got, err)
+			}
+		}
+
+		checkTruncated(t, buf.Bytes(), func(r io.Reader) error {
+			_, err := ReadVarLong(r)
+			return err
+		})
+	})
+}
+
+func FuzzReadString(f *testing.F) {
+	for _, seed := range [][]byte{
+		{0x00},
+		{0x05, 'h', 'e', 'l', 'l', 'o'},
+		{0x05, 'h', 'e'},
+		{0xff, 0xff, 0xff, 0xff, 0x07},
+		{0xff, 0xff, 0xff, 0xff, 0x0f},
+		{0x04, 0xf0, 0x9f, 0x98, 0x80},
+	} {
+		f.Add(seed, 16)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte, max int) {
+		if max <= 0 || max > MaxStringLength {
+			max = MaxStringLength
+		}
+
+		for name, r := range readers(data) {
+			val, err := ReadStringMax(r, max)
+			switch {
+			case err == nil:
+				if UTF16Len(val) > max {
+					t.Fatalf("%s: read string of %d code units over the maximum of %d", name, UTF16Len(val), max)
+				}
+				// The string must be the bytes after its length.
+				n, _ := ReadVarInt(bytes.NewReader(data))
+				start := len(data) - len(val) - remaining(r)
+				if n != len(val) || start < 1 || string(data[start:start+len(val)]) != val {
+					t.Fatalf("%s: misread string %q from %x", name, val, data)
+				}
+			case errors.Is(err, ErrStringTooLong), err == ErrInvalidLength, err == ErrOverflow, err == ErrTruncated, err == io.EOF:
+			default:
+				t.Fatalf("%s: unexpected error %v", name, err)
+			}
+		}
+	})
+}
+
+// remaining will return the number of unread bytes of a reader from readers.
+func remaining(r io.Reader) int {
+	switch r := r.(type) {
+	case *Buffer:
+		return r.Len()
+	case *bytes.Reader:
+		return r.Len()
+	}
+
+	n, _ := io.Copy(io.Discard, r)
+	return int(n)
+}
+
+func FuzzWriteString(f *testing.F) {
+	for _, seed := range []string{"", "hello", "\U0001F600", "héllo wörld", string([]byte{0xff, 0xfe})} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, val string) {
+		var buf bytes.Buffer
+		err := WriteStringMax(&buf, val, 16)
+		if UTF16Len(val) > 16 {
+			if err != ErrStringTooLong {
+				t.Fatalf("wrote %d code units over the maximum of 16, %v", UTF16Len(val), err)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, r := range readers(buf.Bytes()) {
+			got, err := ReadStringMax(r, 16)
+			if err != nil || got != val {
+				t.Fatalf("%s: %q read back as %q, %v", name, val, got, err)
+			}
+		}
+
+		checkTruncated(t, buf.Bytes(), func(r io.Reader) error {
+			_, err := ReadStringMax(r, 16)
+			return err
+		})
+	})
+}
+
+func FuzzReadFixed(f *testing.F) {
+	f.Add(uint64(0))
+	f.Add(uint64(math.MaxUint64))
+	f.Add(math.Float64bits(math.Pi))
+	f.Add(uint64(0x0102030405060708))
+
+	f.Fuzz(func(t *testing.T, val uint64) {
+		// want is the value read back, as the bits of its type.
+		type fixed struct {
+			want  uint64
+			write func(io.Writer) error
+			read  func(io.Reader) (uint64, error)
+		}
+
+		for name, fx := range map[string]fixed{
+			"Bool": {
+				val & 1,
+				func(w io.Writer) error { return WriteBool(w, val&1 != 0) },
+				func(r io.Reader) (uint64, error) {
+					v, err := ReadBool(r)
+					if v {
+						return 1, err
+					}
+					return 0, err
+				},
+			},
+			"Int8": {
+				uint64(uint8(val)),
+				func(w io.Writer) error { return WriteInt8(w, int8(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadInt8(r); return uint64(uint8(v)), err },
+			},
+			"Uint8": {
+				uint64(uint8(val)),
+				func(w io.Writer) error { return WriteUint8(w, uint8(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadUint8(r); return uint64(v), err },
+			},
+			"Int16": {
+				uint64(uint16(val)),
+				func(w io.Writer) error { return WriteInt16(w, int16(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadInt16(r); return uint64(uint16(v)), err },
+			},
+			"Uint16": {
+				uint64(uint16(val)),
+				func(w io.Writer) error { return WriteUint16(w, uint16(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadUint16(r); return uint64(v), err },
+			},
+			"Int32": {
+				uint64(uint32(val)),
+				func(w io.Writer) error { return WriteInt32(w, int32(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadInt32(r); return uint64(uint32(v)), err },
+			},
+			"Uint32": {
+				uint64(uint32(val)),
+				func(w io.Writer) error { return WriteUint32(w, uint32(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadUint32(r); return uint64(v), err },
+			},
+			"Int64": {
+				val,
+				func(w io.Writer) error { return WriteInt64(w, int64(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadInt64(r); return uint64(v), err },
+			},
+			"Uint64": {
+				val,
+				func(w io.Writer) error { return WriteUint64(w, val) },
+				func(r io.Reader) (uint64, error) { return ReadUint64(r) },
+			},
+			"Float32": {
+				uint64(uint32(val)),
+				func(w io.Writer) error { return WriteFloat32(w, math.Float32frombits(uint32(val))) },
+				func(r io.Reader) (uint64, error) { v, err := ReadFloat32(r); return uint64(math.Float32bits(v)), err },
+			},
+			"Float64": {
+				val,
+				func(w io.Writer) error { return WriteFloat64(w, math.Float64frombits(val)) },
+				func(r io.Reader) (uint64, error) { v, err := ReadFloat64(r); return math.Float64bits(v), err },
+			},
+		} {
+			var buf bytes.Buffer
+			if err := fx.write(&buf); err != nil {
+				t.Fatal(err)
+			}
+
+			for kind, r := range readers(buf.Bytes()) {
+				got, err := fx.read(r)
+				if err != nil || got != fx.want {
+					t.Fatalf("%s from %s: read %x, want %x, %v", name, kind, got, fx.want, err)
+				}
+			}
+
+			checkTruncated(t, buf.Bytes(), func(r io.Reader) error {
+				_, err := fx.read(r)
+				return err
+			})
+		}
+	})
+}
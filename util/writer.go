@@ -6,8 +6,22 @@ import (
 	"math"
 )
 
-// WriteString will write the string to the writer
+// WriteString will write the string to the writer, it may hold at most MaxStringLength characters
 func WriteString(writer io.Writer, val string) (err error) {
+	return WriteStringMax(writer, val, MaxStringLength)
+}
+
+// WriteStringMax will write the string of at most max UTF-16 code units to the writer
+func WriteStringMax(writer io.Writer, val string, max int) (err error) {
+	if len(val) > max && UTF16Len(val) > max {
+		return ErrStringTooLong
+	}
+
+	if b, ok := writer.(*Buffer); ok {
+		b.PutString(val)
+		return nil
+	}
+
 	bytes := []byte(val)
 	err = WriteVarInt(writer, len(bytes))
 	if err != nil {
@@ -20,14 +34,34 @@ func WriteString(writer io.Writer, val string) (err error) {
 
 // WriteVarInt will write the int to the writer
 func WriteVarInt(writer io.Writer, val int) (err error) {
-	for val >= 0x80 {
-		err = WriteUint8(writer, byte(val)|0x80)
+	if b, ok := writer.(*Buffer); ok {
+		b.PutVarInt(val)
+		return nil
+	}
+
+	uval := uint32(val)
+	for uval >= 0x80 {
+		err = WriteUint8(writer, byte(uval)|0x80)
 		if err != nil {
 			return
 		}
-		val >>= 7
+		uval >>= 7
 	}
-	err = WriteUint8(writer, byte(val))
+	err = WriteUint8(writer, byte(uval))
+	return
+}
+
+// WriteVarLong will write the int64 to the writer
+func WriteVarLong(writer io.Writer, val int64) (err error) {
+	uval := uint64(val)
+	for uval >= 0x80 {
+		err = WriteUint8(writer, byte(uval)|0x80)
+		if err != nil {
+			return
+		}
+		uval >>= 7
+	}
+	err = WriteUint8(writer, byte(uval))
 	return
 }
 
@@ -49,6 +83,10 @@ func WriteInt8(writer io.Writer, val int8) (err error) {
 
 // WriteUint8 will write the uint8 to the writer
 func WriteUint8(writer io.Writer, val uint8) (err error) {
+	if bw, ok := writer.(io.ByteWriter); ok {
+		return bw.WriteByte(val)
+	}
+
 	var protocol [1]byte
 	protocol[0] = val
 	_, err = writer.Write(protocol[:1])
@@ -63,6 +101,11 @@ func WriteInt16(writer io.Writer, val int16) (err error) {
 
 // WriteUint16 will write the uint16 to the writer
 func WriteUint16(writer io.Writer, val uint16) (err error) {
+	if b, ok := writer.(*Buffer); ok {
+		b.PutUint16(val)
+		return nil
+	}
+
 	var protocol [2]byte
 	binary.BigEndian.PutUint16(protocol[:2], val)
 	_, err = writer.Write(protocol[:2])
@@ -77,6 +120,11 @@ func WriteInt32(writer io.Writer, val int32) (err error) {
 
 // WriteUint32 will write the uint32 to the writer
 func WriteUint32(writer io.Writer, val uint32) (err error) {
+	if b, ok := writer.(*Buffer); ok {
+		b.PutUint32(val)
+		return nil
+	}
+
 	var protocol [4]byte
 	binary.BigEndian.PutUint32(protocol[:4], val)
 	_, err = writer.Write(protocol[:4])
@@ -91,6 +139,11 @@ func WriteInt64(writer io.Writer, val int64) (err error) {
 
 // WriteUint64 will write the uint64 to the writer
 func WriteUint64(writer io.Writer, val uint64) (err error) {
+	if b, ok := writer.(*Buffer); ok {
+		b.PutUint64(val)
+		return nil
+	}
+
 	var protocol [8]byte
 	binary.BigEndian.PutUint64(protocol[:8], val)
 	_, err = writer.Write(protocol[:8])
@@ -0,0 +1,62 @@
+package anvil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// Compress will compress the data with the compression type.
+func Compress(compression byte, data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZlib:
+		w = zlib.NewWriter(&buf)
+	case CompressionNone:
+		return append([]byte(nil), data...), nil
+	case CompressionLZ4:
+		return compressLZ4Block(data), nil
+	default:
+		return nil, ErrUnknownCompression
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress will decompress the data compressed with the compression type.
+func Decompress(compression byte, data []byte) ([]byte, error) {
+	var (
+		r   io.Reader
+		err error
+	)
+	switch compression {
+	case CompressionGzip:
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	case CompressionZlib:
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case CompressionNone:
+		return data, nil
+	case CompressionLZ4:
+		return decompressLZ4Block(data)
+	default:
+		return nil, ErrUnknownCompression
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,454 @@
+package anvil
+
+import (
+	"errors"
+	"fmt"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/world/chunk"
+)
+
+// ErrUnknownName is returned for block states, biomes and block entities
+// which have no ID, or IDs which have no name.
+var ErrUnknownName = errors.New("anvil: unknown name")
+
+// Data versions at which the chunk format changed.
+const (
+	// dataVersion3DBiomes moved biomes to 4x4x4 cells, in 19w36a.
+	dataVersion3DBiomes = 2203
+	// dataVersionNoSpan stopped values from spanning two longs, in 20w17a.
+	dataVersionNoSpan = 2527
+	// DataVersion1_18 moved the chunk out of the Level compound and biomes
+	// into sections, the format FromColumn saves in. It is the lowest data
+	// version to save with.
+	DataVersion1_18 = 2860
+)
+
+// IDs maps the names chunks are saved with to the IDs sent to clients.
+type IDs interface {
+	BlockState(name string, properties map[string]string) (int32, bool)
+	Biome(name string) (int32, bool)
+	BlockEntity(name string) (int32, bool)
+}
+
+// Names maps the IDs of a column back to the names it is saved with.
+type Names interface {
+	BlockStateName(id int32) (name string, properties map[string]string, ok bool)
+	BiomeName(id int32) (string, bool)
+	BlockEntityName(id int32) (string, bool)
+}
+
+// ToColumn will convert a chunk of a region file to a column of the
+// dimension. Chunks from before 1.13 keep their block IDs and metadata as
+// state IDs, and chunks from before 1.18 their numeric biome IDs.
+func ToColumn(c nbt.Compound, dim chunk.Dimension, ids IDs) (*chunk.Column, error) {
+	if _, ok := c["sections"]; ok {
+		return modernColumn(c, dim, ids)
+	}
+
+	level, ok := c.Compound("Level")
+	if !ok {
+		return nil, ErrCorrupt
+	}
+	dataVersion, _ := c.Number("DataVersion")
+
+	x, _ := level.Number("xPos")
+	z, _ := level.Number("zPos")
+	col := chunk.NewColumn(int32(x), int32(z), dim.MinY, dim.Height)
+
+	sections, _ := level.List("Sections")
+	for _, item := range sections {
+		s, ok := item.(nbt.Compound)
+		if !ok {
+			return nil, ErrCorrupt
+		}
+		y, _ := s.Number("Y")
+		i := int(y) - dim.MinY>>4
+
+		if i >= 0 && i < len(col.Sections) {
+			var err error
+			if _, ok := s["Blocks"]; ok {
+				err = legacyStates(s, col.Sections[i].States)
+			} else if palette, ok := s.List("Palette"); ok {
+				data, _ := s.LongArray("BlockStates")
+				err = readPalette(palette, data, 4, dataVersion < dataVersionNoSpan, col.Sections[i].States, blockState(ids))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		readLight(col, s, i+1)
+	}
+
+	if biomes, ok := level.IntArray("Biomes"); ok && dataVersion >= dataVersion3DBiomes {
+		for i, b := range biomes {
+			if i/chunk.BiomeVolume >= len(col.Sections) {
+				break
+			}
+			col.Sections[i/chunk.BiomeVolume].Biomes.Set(i%chunk.BiomeVolume, b)
+		}
+	} else if ok && len(biomes) == 256 {
+		flatBiomes(col, func(i int) int32 { return biomes[i] })
+	} else if biomes, ok := level.ByteArray("Biomes"); ok && len(biomes) == 256 {
+		flatBiomes(col, func(i int) int32 { return int32(biomes[i]) })
+	}
+
+	readHeightmaps(col, level)
+	entities, _ := level.List("TileEntities")
+	return col, readBlockEntities(col, entities, ids)
+}
+
+// modernColumn will convert a chunk saved by 1.18 or later.
+func modernColumn(c nbt.Compound, dim chunk.Dimension, ids IDs) (*chunk.Column, error) {
+	x, _ := c.Number("xPos")
+	z, _ := c.Number("zPos")
+	col := chunk.NewColumn(int32(x), int32(z), dim.MinY, dim.Height)
+
+	sections, _ := c.List("sections")
+	for _, item := range sections {
+		s, ok := item.(nbt.Compound)
+		if !ok {
+			return nil, ErrCorrupt
+		}
+		y, _ := s.Number("Y")
+		i := int(y) - dim.MinY>>4
+
+		if i >= 0 && i < len(col.Sections) {
+			if states, ok := s.Compound("block_states"); ok {
+				palette, _ := states.List("palette")
+				data, _ := states.LongArray("data")
+				if err := readPalette(palette, data, 4, false, col.Sections[i].States, blockState(ids)); err != nil {
+					return nil, err
+				}
+			}
+			if biomes, ok := s.Compound("biomes"); ok {
+				palette, _ := biomes.List("palette")
+				data, _ := biomes.LongArray("data")
+				if err := readPalette(palette, data, 0, false, col.Sections[i].Biomes, biome(ids)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		readLight(col, s, i+1)
+	}
+
+	readHeightmaps(col, c)
+	entities, _ := c.List("block_entities")
+	return col, readBlockEntities(col, entities, ids)
+}
+
+// readPalette will set the values of the container from a saved palette and
+// the indexes into it, packed with at least minBits bits. A palette of a
+// single value has no indexes.
+func readPalette(palette nbt.List, data []int64, minBits int, span bool, c *chunk.Container, lookup func(interface{}) (int32, error)) error {
+	if len(palette) == 0 {
+		return ErrCorrupt
+	}
+
+	values := make([]int32, len(palette))
+	for i, item := range palette {
+		v, err := lookup(item)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+
+	if len(values) == 1 || len(data) == 0 {
+		c.Fill(values[0])
+		return nil
+	}
+
+	bits := chunk.BitsFor(len(values))
+	if bits < minBits {
+		bits = minBits
+	}
+	longs := make([]uint64, len(data))
+	for i, v := range data {
+		longs[i] = uint64(v)
+	}
+	size := c.Kind().Size
+	storage, err := chunk.NewBitStorageData(bits, size, span, longs)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < size; i++ {
+		idx := storage.Get(i)
+		if idx >= len(values) {
+			return ErrCorrupt
+		}
+		c.Set(i, values[idx])
+	}
+	return nil
+}
+
+func blockState(ids IDs) func(interface{}) (int32, error) {
+	return func(item interface{}) (int32, error) {
+		state, _ := item.(nbt.Compound)
+		name, _ := state.String("Name")
+
+		props := make(map[string]string)
+		if p, ok := state.Compound("Properties"); ok {
+			for k := range p {
+				props[k], _ = p.String(k)
+			}
+		}
+
+		id, ok := ids.BlockState(name, props)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownName, name)
+		}
+		return id, nil
+	}
+}
+
+func biome(ids IDs) func(interface{}) (int32, error) {
+	return func(item interface{}) (int32, error) {
+		name, _ := item.(string)
+		id, ok := ids.Biome(name)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownName, name)
+		}
+		return id, nil
+	}
+}
+
+// legacyStates will set the block states of a section saved before 1.13,
+// from its block IDs, the high bits of its block IDs over 255 and its
+// metadata nibbles.
+func legacyStates(s nbt.Compound, c *chunk.Container) error {
+	blocks, _ := s.ByteArray("Blocks")
+	add, _ := s.ByteArray("Add")
+	data, _ := s.ByteArray("Data")
+	if len(blocks) != chunk.SectionVolume || len(data) != chunk.SectionVolume/2 || (add != nil && len(add) != chunk.SectionVolume/2) {
+		return ErrCorrupt
+	}
+
+	nibble := func(arr []byte, i int) int32 {
+		return int32(arr[i>>1]>>(uint(i&1)*4)) & 15
+	}
+	for i, b := range blocks {
+		id := int32(b)
+		if add != nil {
+			id |= nibble(add, i) << 8
+		}
+		c.Set(i, id<<4|nibble(data, i))
+	}
+
+	return nil
+}
+
+// flatBiomes will set the biomes of the column from the biome of every
+// block column, in ZX order, as saved before 19w36a.
+func flatBiomes(col *chunk.Column, biome func(i int) int32) {
+	for _, s := range col.Sections {
+		for i := 0; i < chunk.BiomeVolume; i++ {
+			x, z := i&3*4, i>>2&3*4
+			s.Biomes.Set(i, biome(z<<4|x))
+		}
+	}
+}
+
+// readLight will set the light of the section at light index i.
+func readLight(col *chunk.Column, s nbt.Compound, i int) {
+	if i < 0 || i >= len(col.SkyLight) {
+		return
+	}
+
+	if light, ok := s.ByteArray("BlockLight"); ok && len(light) == chunk.SectionVolume/2 {
+		col.BlockLight[i] = append(chunk.NibbleArray(nil), light...)
+	}
+	if light, ok := s.ByteArray("SkyLight"); ok && len(light) == chunk.SectionVolume/2 {
+		col.SkyLight[i] = append(chunk.NibbleArray(nil), light...)
+	}
+}
+
+func readHeightmaps(col *chunk.Column, c nbt.Compound) {
+	maps, _ := c.Compound("Heightmaps")
+	for name := range maps {
+		if data, ok := maps.LongArray(name); ok {
+			col.Heightmaps[name] = data
+		}
+	}
+}
+
+// readBlockEntities will add the block entities of the column, without
+// their id and position in their data.
+func readBlockEntities(col *chunk.Column, entities nbt.List, ids IDs) error {
+	for _, item := range entities {
+		e, ok := item.(nbt.Compound)
+		if !ok {
+			return ErrCorrupt
+		}
+
+		name, _ := e.String("id")
+		typ, ok := ids.BlockEntity(name)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownName, name)
+		}
+		x, _ := e.Number("x")
+		y, _ := e.Number("y")
+		z, _ := e.Number("z")
+
+		data := make(nbt.Compound, len(e))
+		for k, v := range e {
+			switch k {
+			case "id", "x", "y", "z", "keepPacked":
+			default:
+				data[k] = v
+			}
+		}
+
+		col.BlockEntities = append(col.BlockEntities, chunk.BlockEntity{X: int(x) & 15, Y: int(y), Z: int(z) & 15, Type: typ, Data: data})
+	}
+
+	return nil
+}
+
+// FromColumn will convert a column to a chunk in the format of 1.18 and
+// later, to be saved with the data version.
+func FromColumn(col *chunk.Column, names Names, dataVersion int32) (nbt.Compound, error) {
+	minSection := col.MinY >> 4
+
+	sections := make(nbt.List, 0, len(col.SkyLight))
+	light := false
+	for li := range col.SkyLight {
+		s := nbt.Compound{"Y": int8(minSection + li - 1)}
+
+		if i := li - 1; i >= 0 && i < len(col.Sections) {
+			states, err := writePalette(col.Sections[i].States, 4, func(id int32) (interface{}, error) {
+				name, props, ok := names.BlockStateName(id)
+				if !ok {
+					return nil, fmt.Errorf("%w: block state %d", ErrUnknownName, id)
+				}
+
+				state := nbt.Compound{"Name": name}
+				if len(props) > 0 {
+					p := make(nbt.Compound, len(props))
+					for k, v := range props {
+						p[k] = v
+					}
+					state["Properties"] = p
+				}
+				return state, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			biomes, err := writePalette(col.Sections[i].Biomes, 0, func(id int32) (interface{}, error) {
+				name, ok := names.BiomeName(id)
+				if !ok {
+					return nil, fmt.Errorf("%w: biome %d", ErrUnknownName, id)
+				}
+				return name, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			s["block_states"], s["biomes"] = states, biomes
+		}
+
+		if col.BlockLight[li] != nil {
+			s["BlockLight"] = []byte(col.BlockLight[li])
+			light = true
+		}
+		if col.SkyLight[li] != nil {
+			s["SkyLight"] = []byte(col.SkyLight[li])
+			light = true
+		}
+		sections = append(sections, s)
+	}
+
+	heightmaps := make(nbt.Compound, len(col.Heightmaps))
+	for name, data := range col.Heightmaps {
+		heightmaps[name] = data
+	}
+
+	entities := make(nbt.List, 0, len(col.BlockEntities))
+	for _, e := range col.BlockEntities {
+		name, ok := names.BlockEntityName(e.Type)
+		if !ok {
+			return nil, fmt.Errorf("%w: block entity %d", ErrUnknownName, e.Type)
+		}
+
+		data := make(nbt.Compound, len(e.Data)+4)
+		for k, v := range e.Data {
+			data[k] = v
+		}
+		data["id"] = name
+		data["x"] = col.X*16 + int32(e.X&15)
+		data["y"] = int32(e.Y)
+		data["z"] = col.Z*16 + int32(e.Z&15)
+		entities = append(entities, data)
+	}
+
+	c := nbt.Compound{
+		"DataVersion":    dataVersion,
+		"xPos":           col.X,
+		"zPos":           col.Z,
+		"yPos":           int32(minSection),
+		"Status":         "minecraft:full",
+		"sections":       sections,
+		"Heightmaps":     heightmaps,
+		"block_entities": entities,
+	}
+	if light {
+		c["isLightOn"] = int8(1)
+	}
+
+	return c, nil
+}
+
+// writePalette will return the saved form of a container: its palette in
+// the order values first appear, and their indexes packed with at least
+// minBits bits when there is more than one.
+func writePalette(c *chunk.Container, minBits int, name func(int32) (interface{}, error)) (nbt.Compound, error) {
+	size := c.Kind().Size
+
+	var values []int32
+	index := make(map[int32]int)
+	indexes := make([]int, size)
+	for i := 0; i < size; i++ {
+		v := c.Get(i)
+		idx, ok := index[v]
+		if !ok {
+			idx = len(values)
+			index[v] = idx
+			values = append(values, v)
+		}
+		indexes[i] = idx
+	}
+
+	palette := make(nbt.List, len(values))
+	for i, v := range values {
+		item, err := name(v)
+		if err != nil {
+			return nil, err
+		}
+		palette[i] = item
+	}
+
+	saved := nbt.Compound{"palette": palette}
+	if len(values) == 1 {
+		return saved, nil
+	}
+
+	bits := chunk.BitsFor(len(values))
+	if bits < minBits {
+		bits = minBits
+	}
+	storage := chunk.NewBitStorage(bits, size, false)
+	for i, idx := range indexes {
+		storage.Set(i, idx)
+	}
+
+	data := make([]int64, len(storage.Data()))
+	for i, v := range storage.Data() {
+		data[i] = int64(v)
+	}
+	saved["data"] = data
+	return saved, nil
+}
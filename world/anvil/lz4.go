@@ -0,0 +1,315 @@
+package anvil
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// The game compresses chunks with the LZ4 block streams of lz4-java: blocks
+// of at most 64KiB, each with a header of the magic, a token telling whether
+// the block is compressed, its compressed and uncompressed lengths and an
+// xxHash32 checksum of the uncompressed data, ended by an empty block.
+
+// ErrInvalidLZ4 is returned for LZ4 data which cannot be decompressed.
+var ErrInvalidLZ4 = errors.New("anvil: invalid LZ4 data")
+
+const (
+	lz4Magic        = "LZ4Block"
+	lz4HeaderLength = len(lz4Magic) + 13
+	lz4BlockSize    = 64 * 1024
+	lz4MethodRaw    = 0x10
+	lz4MethodLZ4    = 0x20
+	// lz4Level is the compression level lz4-java writes for 64KiB blocks.
+	lz4Level = 6
+	lz4Seed  = 0x9747b28c
+
+	lz4MinMatch = 4
+	// Matches end at least lz4LastLiterals bytes before the end of the block,
+	// and start at least lz4MatchLimit bytes before it.
+	lz4LastLiterals = 5
+	lz4MatchLimit   = 12
+	lz4HashLog      = 14
+)
+
+// compressLZ4Block will compress the data into an LZ4 block stream.
+func compressLZ4Block(data []byte) []byte {
+	out := make([]byte, 0, len(data)/2+2*lz4HeaderLength)
+	for len(data) > 0 {
+		n := len(data)
+		if n > lz4BlockSize {
+			n = lz4BlockSize
+		}
+		block := data[:n]
+		data = data[n:]
+
+		method, payload := byte(lz4MethodLZ4), lz4Compress(block)
+		if len(payload) >= len(block) {
+			method, payload = lz4MethodRaw, block
+		}
+
+		out = appendLZ4Header(out, method, len(payload), len(block), xxhash32(block, lz4Seed)&0xFFFFFFF)
+		out = append(out, payload...)
+	}
+
+	return appendLZ4Header(out, lz4MethodRaw, 0, 0, 0)
+}
+
+func appendLZ4Header(out []byte, method byte, compressed, size int, checksum uint32) []byte {
+	var header [lz4HeaderLength]byte
+	copy(header[:], lz4Magic)
+	header[8] = method | lz4Level
+	binary.LittleEndian.PutUint32(header[9:], uint32(compressed))
+	binary.LittleEndian.PutUint32(header[13:], uint32(size))
+	binary.LittleEndian.PutUint32(header[17:], checksum)
+
+	return append(out, header[:]...)
+}
+
+// decompressLZ4Block will decompress an LZ4 block stream. A stream without
+// its final empty block ends with its data.
+func decompressLZ4Block(data []byte) ([]byte, error) {
+	var out []byte
+	for len(data) > 0 {
+		if len(data) < lz4HeaderLength || string(data[:len(lz4Magic)]) != lz4Magic {
+			return nil, ErrInvalidLZ4
+		}
+
+		method := data[8] & 0xF0
+		compressed := int(binary.LittleEndian.Uint32(data[9:]))
+		size := int(binary.LittleEndian.Uint32(data[13:]))
+		checksum := binary.LittleEndian.Uint32(data[17:])
+		data = data[lz4HeaderLength:]
+
+		if size == 0 {
+			break
+		}
+		if compressed < 0 || size < 0 || compressed > len(data) || size > 1<<25 {
+			return nil, ErrInvalidLZ4
+		}
+
+		var block []byte
+		switch method {
+		case lz4MethodRaw:
+			if compressed != size {
+				return nil, ErrInvalidLZ4
+			}
+			block = data[:size]
+		case lz4MethodLZ4:
+			var err error
+			if block, err = lz4Decompress(data[:compressed], size); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, ErrInvalidLZ4
+		}
+		if xxhash32(block, lz4Seed)&0xFFFFFFF != checksum {
+			return nil, ErrInvalidLZ4
+		}
+
+		out = append(out, block...)
+		data = data[compressed:]
+	}
+
+	return out, nil
+}
+
+// lz4Compress will compress the block in the LZ4 block format, finding
+// matches with a hash table of the last position of every 4 byte sequence.
+func lz4Compress(src []byte) []byte {
+	dst := make([]byte, 0, len(src)+len(src)/255+16)
+	var table [1 << lz4HashLog]int32
+
+	anchor := 0
+	for i := 0; i+lz4MatchLimit < len(src); {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := seq * 2654435761 >> (32 - lz4HashLog)
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+
+		if ref < 0 || i-ref > 0xFFFF || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i++
+			continue
+		}
+
+		length := lz4MinMatch
+		for i+length < len(src)-lz4LastLiterals && src[ref+length] == src[i+length] {
+			length++
+		}
+
+		dst = lz4AppendSequence(dst, src[anchor:i], i-ref, length)
+		i += length
+		anchor = i
+	}
+
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence will append the literals and the match following them,
+// or only the literals when length is 0.
+func lz4AppendSequence(dst, literals []byte, offset, length int) []byte {
+	token := byte(0)
+	if len(literals) >= 15 {
+		token = 15 << 4
+	} else {
+		token = byte(len(literals)) << 4
+	}
+	matchLength := length - lz4MinMatch
+	if length > 0 {
+		if matchLength >= 15 {
+			token |= 15
+		} else {
+			token |= byte(matchLength)
+		}
+	}
+
+	dst = append(dst, token)
+	if len(literals) >= 15 {
+		dst = lz4AppendLength(dst, len(literals)-15)
+	}
+	dst = append(dst, literals...)
+	if length == 0 {
+		return dst
+	}
+
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if matchLength >= 15 {
+		dst = lz4AppendLength(dst, matchLength-15)
+	}
+	return dst
+}
+
+func lz4AppendLength(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+
+	return append(dst, byte(n))
+}
+
+// lz4Decompress will decompress an LZ4 block of size bytes.
+func lz4Decompress(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	for i := 0; i < len(src); {
+		token := src[i]
+		i++
+
+		literals := int(token >> 4)
+		if literals == 15 {
+			n, read, err := lz4ReadLength(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			literals += n
+			i += read
+		}
+		if literals > len(src)-i || len(dst)+literals > size {
+			return nil, ErrInvalidLZ4
+		}
+		dst = append(dst, src[i:i+literals]...)
+		i += literals
+
+		// The last sequence has no match.
+		if i == len(src) {
+			break
+		}
+		if i+2 > len(src) {
+			return nil, ErrInvalidLZ4
+		}
+		offset := int(src[i]) | int(src[i+1])<<8
+		i += 2
+
+		length := int(token & 15)
+		if length == 15 {
+			n, read, err := lz4ReadLength(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			length += n
+			i += read
+		}
+		length += lz4MinMatch
+
+		if offset == 0 || offset > len(dst) || len(dst)+length > size {
+			return nil, ErrInvalidLZ4
+		}
+		// Matches may overlap the bytes they produce, so they are copied byte by byte.
+		start := len(dst) - offset
+		for j := 0; j < length; j++ {
+			dst = append(dst, dst[start+j])
+		}
+	}
+
+	if len(dst) != size {
+		return nil, ErrInvalidLZ4
+	}
+	return dst, nil
+}
+
+// lz4ReadLength will read the extra bytes of a length and return it and the
+// number of bytes read.
+func lz4ReadLength(src []byte) (n, read int, err error) {
+	for {
+		if read == len(src) {
+			return 0, 0, ErrInvalidLZ4
+		}
+		b := src[read]
+		read++
+		n += int(b)
+		if b != 255 {
+			return
+		}
+	}
+}
+
+const (
+	xxPrime1 uint32 = 2654435761
+	xxPrime2 uint32 = 2246822519
+	xxPrime3 uint32 = 3266489917
+	xxPrime4 uint32 = 668265263
+	xxPrime5 uint32 = 374761393
+)
+
+// xxhash32 will return the xxHash32 of the data.
+func xxhash32(data []byte, seed uint32) uint32 {
+	n := len(data)
+
+	var h uint32
+	if n >= 16 {
+		v1 := seed + xxPrime1
+		v1 += xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for ; len(data) >= 16; data = data[16:] {
+			v1 = xxRound(v1, binary.LittleEndian.Uint32(data))
+			v2 = xxRound(v2, binary.LittleEndian.Uint32(data[4:]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint32(data[8:]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint32(data[12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxPrime5
+	}
+
+	h += uint32(n)
+	for ; len(data) >= 4; data = data[4:] {
+		h += binary.LittleEndian.Uint32(data) * xxPrime3
+		h = bits.RotateLeft32(h, 17) * xxPrime4
+	}
+	for _, b := range data {
+		h += uint32(b) * xxPrime5
+		h = bits.RotateLeft32(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 15
+	h *= xxPrime2
+	h ^= h >> 13
+	h *= xxPrime3
+	h ^= h >> 16
+	return h
+}
+
+func xxRound(acc, input uint32) uint32 {
+	return bits.RotateLeft32(acc+input*xxPrime2, 13) * xxPrime1
+}
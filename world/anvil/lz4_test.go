@@ -0,0 +1,166 @@
+package anvil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"justanother.org/protocolhelper/nbt"
+)
+
+// The fixture testdata/chunk_1_20_5.lz4 holds a chunk of DataVersion 3837
+// (1.20.5) framed as LZ4BlockOutputStream writes it. Its blocks were
+// compressed with LZ4_compress_default of liblz4 1.9.4, which the native
+// compressor of lz4-java calls, and its checksums were taken with XXH32 of
+// libxxhash 0.8.1.
+const (
+	fixtureLength   = 81872
+	fixtureChecksum = 0x07bcbd41 // XXH32 of the whole chunk with lz4Seed
+)
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/chunk_1_20_5.lz4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// lz4Header is a block header of an LZ4 block stream.
+type lz4Header struct {
+	token            byte
+	compressed, size int
+	checksum         uint32
+}
+
+// lz4Headers will return the block headers of the stream.
+func lz4Headers(t *testing.T, data []byte) []lz4Header {
+	t.Helper()
+
+	var headers []lz4Header
+	for len(data) > 0 {
+		if len(data) < lz4HeaderLength || string(data[:len(lz4Magic)]) != lz4Magic {
+			t.Fatalf("block %d: no header", len(headers))
+		}
+
+		h := lz4Header{
+			token:      data[8],
+			compressed: int(binary.LittleEndian.Uint32(data[9:])),
+			size:       int(binary.LittleEndian.Uint32(data[13:])),
+			checksum:   binary.LittleEndian.Uint32(data[17:]),
+		}
+		headers = append(headers, h)
+		data = data[lz4HeaderLength+h.compressed:]
+	}
+
+	return headers
+}
+
+func TestXXHash32(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0x02cc5d05},
+		{"a", 0, 0x550d7456},
+		{"abc", 0, 0x32d153ff},
+		{"0123456789abcdef", 0, 0xc2c45b69},
+		{"The quick brown fox jumps over the lazy dog", 0, 0xe85ea4de},
+		{"", lz4Seed, 0x8d3b42d8},
+		{"a", lz4Seed, 0x12b7e114},
+		{"abc", lz4Seed, 0x4d4cb222},
+		{"0123456789abcdef", lz4Seed, 0x59ac4ea7},
+		{"The quick brown fox jumps over the lazy dog", lz4Seed, 0xc8579d72},
+	} {
+		if got := xxhash32([]byte(tt.data), tt.seed); got != tt.want {
+			t.Errorf("xxhash32(%q, %#x) = %#08x, want %#08x", tt.data, tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestDecompressLZ4Fixture(t *testing.T) {
+	data, err := Decompress(CompressionLZ4, readFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != fixtureLength {
+		t.Fatalf("got %d bytes, want %d", len(data), fixtureLength)
+	}
+	if got := xxhash32(data, lz4Seed); got != fixtureChecksum {
+		t.Fatalf("got checksum %#08x, want %#08x", got, fixtureChecksum)
+	}
+
+	c, err := nbt.ReadCompound(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.Number("DataVersion"); v != 3837 {
+		t.Errorf("got DataVersion %d, want 3837", v)
+	}
+	if x, _ := c.Number("xPos"); x != -3 {
+		t.Errorf("got xPos %d, want -3", x)
+	}
+	if z, _ := c.Number("zPos"); z != 7 {
+		t.Errorf("got zPos %d, want 7", z)
+	}
+	if sections, _ := c.List("sections"); len(sections) != 24 {
+		t.Errorf("got %d sections, want 24", len(sections))
+	}
+}
+
+func TestDecompressLZ4Corrupt(t *testing.T) {
+	fixture := readFixture(t)
+
+	// A payload byte of the first block, which fails its checksum or its decoding.
+	data := append([]byte(nil), fixture...)
+	data[lz4HeaderLength+100] ^= 0x01
+	if _, err := Decompress(CompressionLZ4, data); err != ErrInvalidLZ4 {
+		t.Errorf("corrupt payload: got error %v, want %v", err, ErrInvalidLZ4)
+	}
+
+	data = append([]byte(nil), fixture...)
+	data[17] ^= 0x01
+	if _, err := Decompress(CompressionLZ4, data); err != ErrInvalidLZ4 {
+		t.Errorf("corrupt checksum: got error %v, want %v", err, ErrInvalidLZ4)
+	}
+}
+
+// TestCompressLZ4Framing will check that the blocks written for the fixture's
+// chunk carry the headers lz4-java writes, apart from the compressed lengths
+// which depend on the matches found.
+func TestCompressLZ4Framing(t *testing.T) {
+	fixture := readFixture(t)
+	data, err := Decompress(CompressionLZ4, fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressed, err := Compress(CompressionLZ4, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, want := lz4Headers(t, compressed), lz4Headers(t, fixture)
+	if len(got) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		g.compressed, w.compressed = 0, 0
+		if g != w {
+			t.Errorf("block %d: got header %+v, want %+v", i, g, w)
+		}
+	}
+
+	roundTrip, err := Decompress(CompressionLZ4, compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(roundTrip, data) {
+		t.Fatal("round trip changed the data")
+	}
+}
@@ -0,0 +1,333 @@
+// Package anvil reads and writes the region files worlds are saved in since
+// 1.2, and converts the chunks they hold to and from the chunk model.
+//
+// A region file holds the 32x32 chunks of a region. It starts with a header
+// of two 4KiB tables, the location and the modification time of every chunk,
+// followed by the chunks in 4KiB sectors. Each chunk is an NBT compound, gzip,
+// zlib or LZ4 compressed or stored as is. Chunks over 1MiB are stored in a
+// .mcc file of their own next to the region file.
+package anvil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"justanother.org/protocolhelper/nbt"
+)
+
+// Compression types of the chunks of a region file.
+const (
+	CompressionGzip = 1
+	CompressionZlib = 2
+	CompressionNone = 3
+	// CompressionLZ4 is supported by the game since 1.20.5.
+	CompressionLZ4 = 4
+
+	// external is set on the compression type of chunks stored in a .mcc file.
+	external = 128
+)
+
+const (
+	sectorSize = 4096
+	// maxSectors is the largest number of sectors the header can point to.
+	maxSectors = 255
+)
+
+// Possible Errors.
+var (
+	// ErrNotFound is returned when the region holds no chunk at the position.
+	ErrNotFound = errors.New("anvil: chunk not found")
+	// ErrCorrupt is returned for region files and chunks which cannot be read.
+	ErrCorrupt = errors.New("anvil: corrupt region file")
+	// ErrUnknownCompression is returned for compression types which are not supported.
+	ErrUnknownCompression = errors.New("anvil: unknown compression type")
+	// ErrReadOnly is returned when writing to a region opened with Open.
+	ErrReadOnly = errors.New("anvil: region is read only")
+)
+
+// Region is an open region file.
+type Region struct {
+	// X and Z are the coordinates of the region, from the name of its file.
+	X, Z int
+
+	file     *os.File
+	dir      string
+	writable bool
+
+	locations  [1024]uint32
+	timestamps [1024]uint32
+	used       []bool
+}
+
+// Open will open the region file for reading.
+func Open(path string) (*Region, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRegion(f, path, false)
+}
+
+// OpenWritable will open the region file for reading and writing, creating
+// it if it does not exist.
+func OpenWritable(path string) (*Region, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return newRegion(f, path, true)
+}
+
+func newRegion(f *os.File, path string, writable bool) (*Region, error) {
+	r := &Region{file: f, dir: filepath.Dir(path), writable: writable}
+	// Files named otherwise are taken to be region 0, 0.
+	fmt.Sscanf(filepath.Base(path), "r.%d.%d.mca", &r.X, &r.Z)
+
+	var header [2 * sectorSize]byte
+	_, err := io.ReadFull(f, header[:])
+	switch {
+	case err == io.EOF && writable:
+		if _, err = f.WriteAt(header[:], 0); err != nil {
+			f.Close()
+			return nil, err
+		}
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		f.Close()
+		return nil, ErrCorrupt
+	case err != nil:
+		f.Close()
+		return nil, err
+	}
+
+	r.used = []bool{true, true}
+	for i := range r.locations {
+		r.locations[i] = binary.BigEndian.Uint32(header[i*4:])
+		r.timestamps[i] = binary.BigEndian.Uint32(header[sectorSize+i*4:])
+
+		offset, count := r.sectors(i)
+		if offset < 2 {
+			r.locations[i] = 0
+			continue
+		}
+		r.mark(offset, count, true)
+	}
+
+	return r, nil
+}
+
+// Close will close the region file.
+func (r *Region) Close() error {
+	return r.file.Close()
+}
+
+// index will return the header index of the chunk, from its coordinates
+// within the region or in the world.
+func index(x, z int) int {
+	return (z&31)<<5 | x&31
+}
+
+// sectors will return the first sector and the number of sectors of chunk i.
+func (r *Region) sectors(i int) (int, int) {
+	return int(r.locations[i] >> 8), int(r.locations[i] & 0xFF)
+}
+
+// mark will mark the sectors as used or free.
+func (r *Region) mark(offset, count int, used bool) {
+	for len(r.used) < offset+count {
+		r.used = append(r.used, false)
+	}
+	for i := offset; i < offset+count; i++ {
+		r.used[i] = used
+	}
+}
+
+// HasChunk will report whether the region holds the chunk.
+func (r *Region) HasChunk(x, z int) bool {
+	return r.locations[index(x, z)] != 0
+}
+
+// Timestamp will return the time the chunk was last saved.
+func (r *Region) Timestamp(x, z int) time.Time {
+	return time.Unix(int64(r.timestamps[index(x, z)]), 0)
+}
+
+// ReadChunk will return the uncompressed NBT data of the chunk.
+func (r *Region) ReadChunk(x, z int) ([]byte, error) {
+	i := index(x, z)
+	offset, count := r.sectors(i)
+	if r.locations[i] == 0 {
+		return nil, ErrNotFound
+	}
+
+	var header [5]byte
+	if _, err := r.file.ReadAt(header[:], int64(offset)*sectorSize); err != nil {
+		return nil, ErrCorrupt
+	}
+	length := int(binary.BigEndian.Uint32(header[:]))
+	if length < 1 || length+4 > count*sectorSize {
+		return nil, ErrCorrupt
+	}
+	compression := header[4]
+
+	var data []byte
+	if compression&external != 0 {
+		var err error
+		if data, err = os.ReadFile(r.externalPath(x, z)); err != nil {
+			return nil, err
+		}
+		compression &^= external
+	} else {
+		data = make([]byte, length-1)
+		if _, err := r.file.ReadAt(data, int64(offset)*sectorSize+5); err != nil {
+			return nil, ErrCorrupt
+		}
+	}
+
+	return Decompress(compression, data)
+}
+
+// Chunk will read the NBT compound of the chunk.
+func (r *Region) Chunk(x, z int) (nbt.Compound, error) {
+	data, err := r.ReadChunk(x, z)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := nbt.ReadCompound(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// WriteChunk will save the NBT data of the chunk, compressed with the
+// compression type, and set its timestamp to now.
+func (r *Region) WriteChunk(x, z int, data []byte, compression byte) error {
+	if !r.writable {
+		return ErrReadOnly
+	}
+
+	compressed, err := Compress(compression, data)
+	if err != nil {
+		return err
+	}
+
+	i := index(x, z)
+	path := r.externalPath(x, z)
+	payload := compressed
+	if (len(compressed)+5+sectorSize-1)/sectorSize > maxSectors {
+		if err = os.WriteFile(path, compressed, 0o644); err != nil {
+			return err
+		}
+		payload, compression = nil, compression|external
+	}
+
+	buf := make([]byte, (len(payload)+5+sectorSize-1)/sectorSize*sectorSize)
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)+1))
+	buf[4] = compression
+	copy(buf[5:], payload)
+
+	// The old sectors stay in use until the header points at the new ones,
+	// so an interrupted write leaves the previous chunk readable.
+	old, oldCount := r.sectors(i)
+	count := len(buf) / sectorSize
+	offset := r.allocate(count)
+	if _, err = r.file.WriteAt(buf, int64(offset)*sectorSize); err != nil {
+		return err
+	}
+	r.mark(offset, count, true)
+
+	hadChunk := r.locations[i] != 0
+	if err = r.setHeader(i, uint32(offset)<<8|uint32(count), uint32(time.Now().Unix())); err != nil {
+		return err
+	}
+	if hadChunk {
+		r.mark(old, oldCount, false)
+	}
+
+	if compression&external == 0 {
+		// A chunk which shrank below the limit leaves its old file behind.
+		if err = os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteCompound will save the NBT compound of the chunk, compressed with the
+// compression type.
+func (r *Region) WriteCompound(x, z int, c nbt.Compound, compression byte) error {
+	var buf bytes.Buffer
+	if err := nbt.Write(&buf, "", c); err != nil {
+		return err
+	}
+
+	return r.WriteChunk(x, z, buf.Bytes(), compression)
+}
+
+// RemoveChunk will remove the chunk from the region.
+func (r *Region) RemoveChunk(x, z int) error {
+	if !r.writable {
+		return ErrReadOnly
+	}
+
+	i := index(x, z)
+	if r.locations[i] == 0 {
+		return nil
+	}
+
+	offset, count := r.sectors(i)
+	r.mark(offset, count, false)
+	if err := os.Remove(r.externalPath(x, z)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return r.setHeader(i, 0, 0)
+}
+
+// allocate will return the first run of count free sectors.
+func (r *Region) allocate(count int) int {
+	run := 0
+	for i, used := range r.used {
+		if used {
+			run = 0
+			continue
+		}
+
+		run++
+		if run == count {
+			return i - count + 1
+		}
+	}
+
+	return len(r.used) - run
+}
+
+func (r *Region) setHeader(i int, location, timestamp uint32) error {
+	r.locations[i], r.timestamps[i] = location, timestamp
+
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], location)
+	if _, err := r.file.WriteAt(b[:], int64(i)*4); err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint32(b[:], timestamp)
+	_, err := r.file.WriteAt(b[:], sectorSize+int64(i)*4)
+	return err
+}
+
+// externalPath will return the path of the .mcc file of the chunk.
+func (r *Region) externalPath(x, z int) string {
+	return filepath.Join(r.dir, fmt.Sprintf("c.%d.%d.mcc", r.X*32+x&31, r.Z*32+z&31))
+}
@@ -0,0 +1,274 @@
+// Package blocks maps block states to the global IDs chunk and block change
+// packets carry, which change with every version that adds blocks.
+//
+// The mapping of a version is loaded from the blocks.json report of its
+// data generator, run with
+//
+//	java -DbundlerMainClass=net.minecraft.data.Main -jar server.jar --reports
+//
+// and block states are written like in commands, such as
+// "minecraft:oak_stairs[facing=north,half=bottom]".
+package blocks
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Possible Errors.
+var (
+	// ErrInvalidState is returned for block state strings which cannot be parsed.
+	ErrInvalidState = errors.New("blocks: invalid block state")
+	// ErrInvalidReport is returned for reports which are not in the layout of blocks.json.
+	ErrInvalidReport = errors.New("blocks: invalid blocks report")
+)
+
+// Block is a block and the properties its states are made of.
+type Block struct {
+	Name       string
+	Properties map[string][]string
+	// Default is the ID of the state the block is placed in.
+	Default int32
+	// States are the IDs of the states of the block.
+	States []int32
+}
+
+// State is a block state.
+type State struct {
+	ID         int32
+	Block      *Block
+	Properties map[string]string
+}
+
+// String will return the state as written in commands.
+func (s *State) String() string {
+	return FormatState(s.Block.Name, s.Properties)
+}
+
+// Registry is the block states of a version.
+type Registry struct {
+	blocks map[string]*Block
+	states []*State
+	ids    map[string]int32
+}
+
+type report map[string]struct {
+	Properties map[string][]string `json:"properties"`
+	States     []struct {
+		ID         int32             `json:"id"`
+		Default    bool              `json:"default"`
+		Properties map[string]string `json:"properties"`
+	} `json:"states"`
+}
+
+// Load will load the blocks.json report at the path.
+func Load(path string) (*Registry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read will read a blocks.json report.
+func Read(r io.Reader) (*Registry, error) {
+	var rep report
+	if err := json.NewDecoder(r).Decode(&rep); err != nil {
+		return nil, err
+	}
+
+	reg := &Registry{
+		blocks: make(map[string]*Block, len(rep)),
+		ids:    make(map[string]int32),
+	}
+	for name, b := range rep {
+		if len(b.States) == 0 {
+			return nil, ErrInvalidReport
+		}
+
+		block := &Block{Name: name, Properties: b.Properties, Default: b.States[0].ID}
+		for _, s := range b.States {
+			if s.ID < 0 {
+				return nil, ErrInvalidReport
+			}
+			if s.Default {
+				block.Default = s.ID
+			}
+			block.States = append(block.States, s.ID)
+
+			for int(s.ID) >= len(reg.states) {
+				reg.states = append(reg.states, nil)
+			}
+			if reg.states[s.ID] != nil {
+				return nil, ErrInvalidReport
+			}
+
+			state := &State{ID: s.ID, Block: block, Properties: s.Properties}
+			reg.states[s.ID] = state
+			reg.ids[state.String()] = s.ID
+		}
+
+		reg.blocks[name] = block
+	}
+
+	return reg, nil
+}
+
+// Len will return the number of state IDs, the highest ID plus one.
+func (r *Registry) Len() int {
+	return len(r.states)
+}
+
+// Block will return the block of the name.
+func (r *Registry) Block(name string) (*Block, bool) {
+	b, ok := r.blocks[namespaced(name)]
+	return b, ok
+}
+
+// State will return the state of the ID.
+func (r *Registry) State(id int32) (*State, bool) {
+	if id < 0 || int(id) >= len(r.states) || r.states[id] == nil {
+		return nil, false
+	}
+
+	return r.states[id], true
+}
+
+// String will return the state of the ID as written in commands, or an
+// empty string for unknown IDs.
+func (r *Registry) String(id int32) string {
+	s, ok := r.State(id)
+	if !ok {
+		return ""
+	}
+
+	return s.String()
+}
+
+// ID will return the ID of a state written as in commands. Properties left
+// out take their value in the default state of the block.
+func (r *Registry) ID(state string) (int32, bool) {
+	name, props, err := ParseState(state)
+	if err != nil {
+		return 0, false
+	}
+
+	return r.BlockState(name, props)
+}
+
+// BlockState will return the ID of the state of the block with the
+// properties. Properties left out take their value in the default state of
+// the block.
+func (r *Registry) BlockState(name string, properties map[string]string) (int32, bool) {
+	b, ok := r.blocks[namespaced(name)]
+	if !ok {
+		return 0, false
+	}
+	if len(properties) == 0 {
+		return b.Default, true
+	}
+
+	props := make(map[string]string, len(b.Properties))
+	for k, v := range r.states[b.Default].Properties {
+		props[k] = v
+	}
+	for k, v := range properties {
+		if _, ok := props[k]; !ok {
+			return 0, false
+		}
+		props[k] = v
+	}
+
+	id, ok := r.ids[FormatState(b.Name, props)]
+	return id, ok
+}
+
+// BlockStateName will return the block and the properties of the state of the ID.
+func (r *Registry) BlockStateName(id int32) (string, map[string]string, bool) {
+	s, ok := r.State(id)
+	if !ok {
+		return "", nil, false
+	}
+
+	return s.Block.Name, s.Properties, true
+}
+
+// ParseState will parse a block state written as in commands, such as
+// "minecraft:oak_stairs[facing=north]". The minecraft namespace is added to
+// names without one.
+func ParseState(s string) (name string, properties map[string]string, err error) {
+	s = strings.TrimSpace(s)
+	name = s
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		if !strings.HasSuffix(s, "]") {
+			return "", nil, ErrInvalidState
+		}
+		name = s[:i]
+
+		properties = make(map[string]string)
+		if inner := strings.TrimSpace(s[i+1 : len(s)-1]); inner != "" {
+			for _, p := range strings.Split(inner, ",") {
+				kv := strings.SplitN(p, "=", 2)
+				if len(kv) != 2 {
+					return "", nil, ErrInvalidState
+				}
+
+				k, v := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+				if k == "" || v == "" {
+					return "", nil, ErrInvalidState
+				}
+				properties[k] = v
+			}
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, "[]=,") {
+		return "", nil, ErrInvalidState
+	}
+
+	return namespaced(name), properties, nil
+}
+
+// FormatState will write a block state as in commands, its properties
+// sorted by name like the game does.
+func FormatState(name string, properties map[string]string) string {
+	if len(properties) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteByte('[')
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(properties[k])
+	}
+	sb.WriteByte(']')
+
+	return sb.String()
+}
+
+func namespaced(name string) string {
+	if strings.Contains(name, ":") {
+		return name
+	}
+
+	return "minecraft:" + name
+}
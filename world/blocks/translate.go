@@ -0,0 +1,81 @@
+package blocks
+
+import "justanother.org/protocolhelper/world/chunk"
+
+// Translator translates the state IDs of one version to those of another,
+// such as for a proxy between clients and servers of different versions.
+type Translator struct {
+	// Fallback is the ID of states the target version has no block for, by
+	// default its air.
+	Fallback int32
+
+	from, to *Registry
+	table    []int32
+}
+
+// NewTranslator will create a translator from the states of one registry to
+// another. States are matched by their block and properties. States whose
+// properties changed take the default state of their block, keeping the
+// properties it still has.
+func NewTranslator(from, to *Registry) *Translator {
+	t := &Translator{from: from, to: to, table: make([]int32, len(from.states))}
+	t.Fallback, _ = to.BlockState("minecraft:air", nil)
+
+	for id, s := range from.states {
+		t.table[id] = -1
+		if s == nil {
+			continue
+		}
+
+		if v, ok := to.ids[s.String()]; ok {
+			t.table[id] = v
+			continue
+		}
+
+		b, ok := to.blocks[s.Block.Name]
+		if !ok {
+			continue
+		}
+		props := make(map[string]string)
+		for k, v := range to.states[b.Default].Properties {
+			props[k] = v
+			if old, ok := s.Properties[k]; ok && hasValue(b.Properties[k], old) {
+				props[k] = old
+			}
+		}
+		t.table[id] = to.ids[FormatState(b.Name, props)]
+	}
+
+	return t
+}
+
+func hasValue(values []string, v string) bool {
+	for _, val := range values {
+		if val == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Translate will return the ID of the state in the target version.
+func (t *Translator) Translate(id int32) int32 {
+	if id < 0 || int(id) >= len(t.table) || t.table[id] < 0 {
+		return t.Fallback
+	}
+
+	return t.table[id]
+}
+
+// TranslateColumn will translate the block states of the column in place.
+func (t *Translator) TranslateColumn(c *chunk.Column) {
+	for _, s := range c.Sections {
+		kind := s.States.Kind()
+		states := chunk.NewContainer(kind, t.Translate(s.States.Get(0)))
+		for i := 1; i < kind.Size; i++ {
+			states.Set(i, t.Translate(s.States.Get(i)))
+		}
+		s.States = states
+	}
+}
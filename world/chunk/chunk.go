@@ -0,0 +1,42 @@
+// Package chunk models chunk columns, the 16 block wide vertical slices of a
+// world, and encodes them into the packets which send them to clients.
+//
+// A column is a stack of sections of 16x16x16 blocks. Every section holds
+// the global block state IDs of its blocks and the biome IDs of its 4x4x4
+// cells in paletted containers, and the column holds the light of each
+// section and of the sections just below and above it, its heightmaps and
+// its block entities.
+//
+// Columns are decoded from the Chunk Data packets of 1.8 and later, but only
+// encoded into those of 1.18 and later. Older layouts are not written.
+package chunk
+
+import "errors"
+
+// Sizes of a section.
+const (
+	SectionWidth  = 16
+	SectionVolume = SectionWidth * SectionWidth * SectionWidth
+	BiomeVolume   = 4 * 4 * 4
+)
+
+// Possible Errors.
+var (
+	// ErrInvalidStorage is returned when packed data does not fit its bits and size.
+	ErrInvalidStorage = errors.New("chunk: invalid bit storage")
+	// ErrUnsupportedVersion is returned for protocol versions without a chunk format.
+	ErrUnsupportedVersion = errors.New("chunk: unsupported protocol version")
+	// ErrOutOfBounds is returned for positions outside of the column.
+	ErrOutOfBounds = errors.New("chunk: position out of bounds")
+)
+
+// Index will return the index of a block in a section, in YZX order.
+func Index(x, y, z int) int {
+	return (y&15)<<8 | (z&15)<<4 | x&15
+}
+
+// BiomeIndex will return the index of the biome cell holding a block in a
+// section, in YZX order.
+func BiomeIndex(x, y, z int) int {
+	return (y&15)>>2<<4 | (z&15)>>2<<2 | (x&15)>>2
+}
@@ -0,0 +1,226 @@
+package chunk
+
+import "justanother.org/protocolhelper/nbt"
+
+// Section is a 16x16x16 block part of a column.
+type Section struct {
+	States *Container
+	Biomes *Container
+}
+
+// NewSection will create a section filled with air (state 0) and biome 0.
+func NewSection() *Section {
+	return &Section{
+		States: NewContainer(BlockStates, 0),
+		Biomes: NewContainer(Biomes, 0),
+	}
+}
+
+// BlockCount will return the number of blocks which are not air, taken to be
+// state 0 as in every version.
+func (s *Section) BlockCount() int {
+	if s.States.Bits() == 0 {
+		if s.States.Get(0) == 0 {
+			return 0
+		}
+		return SectionVolume
+	}
+
+	count := 0
+	for i := 0; i < SectionVolume; i++ {
+		if s.States.Get(i) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// BlockEntity is a block entity of a column. X and Z are relative to the
+// column, Y is absolute. Data holds its NBT without the position and id
+// tags, as sent to clients.
+type BlockEntity struct {
+	X, Y, Z int
+	Type    int32
+	Data    nbt.Compound
+}
+
+// Column is a chunk column.
+type Column struct {
+	X, Z int32
+	// MinY is the Y of the lowest block, a multiple of 16.
+	MinY     int
+	Sections []*Section
+
+	// SkyLight and BlockLight hold the light of the section below the
+	// column, of every section and of the section above it. Nil entries are
+	// not sent.
+	SkyLight   []NibbleArray
+	BlockLight []NibbleArray
+
+	// Heightmaps are packed by PackHeightmap, by their name.
+	Heightmaps    map[string][]int64
+	BlockEntities []BlockEntity
+}
+
+// NewColumn will create an empty column of height blocks from minY.
+func NewColumn(x, z int32, minY, height int) *Column {
+	c := &Column{
+		X:          x,
+		Z:          z,
+		MinY:       minY,
+		Sections:   make([]*Section, height/SectionWidth),
+		SkyLight:   make([]NibbleArray, height/SectionWidth+2),
+		BlockLight: make([]NibbleArray, height/SectionWidth+2),
+		Heightmaps: make(map[string][]int64),
+	}
+	for i := range c.Sections {
+		c.Sections[i] = NewSection()
+	}
+
+	return c
+}
+
+// Height will return the number of blocks the column is high.
+func (c *Column) Height() int {
+	return len(c.Sections) * SectionWidth
+}
+
+// Section will return the section holding the block at y, or nil.
+func (c *Column) Section(y int) *Section {
+	i := (y - c.MinY) >> 4
+	if y < c.MinY || i >= len(c.Sections) {
+		return nil
+	}
+
+	return c.Sections[i]
+}
+
+// Block will return the block state at the position, relative to the column
+// on X and Z. Blocks outside the column are air.
+func (c *Column) Block(x, y, z int) int32 {
+	s := c.Section(y)
+	if s == nil {
+		return 0
+	}
+
+	return s.States.Get(Index(x, y, z))
+}
+
+// SetBlock will set the block state at the position, relative to the column
+// on X and Z.
+func (c *Column) SetBlock(x, y, z int, state int32) error {
+	s := c.Section(y)
+	if s == nil {
+		return ErrOutOfBounds
+	}
+
+	s.States.Set(Index(x, y, z), state)
+	return nil
+}
+
+// Biome will return the biome at the position, relative to the column on X and Z.
+func (c *Column) Biome(x, y, z int) int32 {
+	s := c.Section(y)
+	if s == nil {
+		return 0
+	}
+
+	return s.Biomes.Get(BiomeIndex(x, y, z))
+}
+
+// SetBiome will set the biome of the 4x4x4 cell holding the position,
+// relative to the column on X and Z.
+func (c *Column) SetBiome(x, y, z int, biome int32) error {
+	s := c.Section(y)
+	if s == nil {
+		return ErrOutOfBounds
+	}
+
+	s.Biomes.Set(BiomeIndex(x, y, z), biome)
+	return nil
+}
+
+// lightIndex will return the index of the light of the section holding y.
+func (c *Column) lightIndex(y int) int {
+	i := (y-c.MinY)>>4 + 1
+	if i < 0 || i >= len(c.Sections)+2 {
+		return -1
+	}
+
+	return i
+}
+
+// SkyLightAt will return the sky light level at the position.
+func (c *Column) SkyLightAt(x, y, z int) int {
+	return lightAt(c.SkyLight, c.lightIndex(y), x, y, z)
+}
+
+// SetSkyLight will set the sky light level at the position.
+func (c *Column) SetSkyLight(x, y, z, level int) error {
+	return setLight(c.SkyLight, c.lightIndex(y), x, y, z, level)
+}
+
+// BlockLightAt will return the block light level at the position.
+func (c *Column) BlockLightAt(x, y, z int) int {
+	return lightAt(c.BlockLight, c.lightIndex(y), x, y, z)
+}
+
+// SetBlockLight will set the block light level at the position.
+func (c *Column) SetBlockLight(x, y, z, level int) error {
+	return setLight(c.BlockLight, c.lightIndex(y), x, y, z, level)
+}
+
+func lightAt(light []NibbleArray, i, x, y, z int) int {
+	if i < 0 || i >= len(light) || light[i] == nil {
+		return 0
+	}
+
+	return light[i].Get(x, y, z)
+}
+
+func setLight(light []NibbleArray, i, x, y, z, level int) error {
+	if i < 0 || i >= len(light) {
+		return ErrOutOfBounds
+	}
+	if light[i] == nil {
+		light[i] = NewNibbleArray()
+	}
+
+	light[i].Set(x, y, z, level)
+	return nil
+}
+
+// PackHeightmap will pack the heights of the columns of blocks, in ZX order,
+// for a column of the height. A height is the number of blocks from the bottom
+// of the column to the top of the highest matching block, 0 for none.
+func PackHeightmap(heights *[256]int, height int) []int64 {
+	storage := NewBitStorage(BitsFor(height+1), 256, false)
+	for i, h := range heights {
+		storage.Set(i, h)
+	}
+
+	data := make([]int64, len(storage.Data()))
+	for i, v := range storage.Data() {
+		data[i] = int64(v)
+	}
+	return data
+}
+
+// ComputeHeightmap will set the heightmap of the name to the top blocks for
+// which match reports true, such as blocks blocking motion for
+// MOTION_BLOCKING or non-air blocks for WORLD_SURFACE.
+func (c *Column) ComputeHeightmap(name string, match func(state int32) bool) {
+	var heights [256]int
+	for z := 0; z < SectionWidth; z++ {
+		for x := 0; x < SectionWidth; x++ {
+			for y := c.MinY + c.Height() - 1; y >= c.MinY; y-- {
+				if match(c.Block(x, y, z)) {
+					heights[z<<4|x] = y - c.MinY + 1
+					break
+				}
+			}
+		}
+	}
+
+	c.Heightmaps[name] = PackHeightmap(&heights, c.Height())
+}
@@ -0,0 +1,178 @@
+package chunk
+
+import "justanother.org/protocolhelper/protocol/version"
+
+// Kind describes a paletted container: the number of values it holds and
+// the bits per entry of its palettes. Containers of up to MaxBits bits use
+// an indirect palette of at least MinBits bits, larger ones store the global
+// IDs directly with at least DirectBits bits, more if an ID needs them. The
+// bits global IDs are sent with depend on the client, see Column.ChunkData.
+type Kind struct {
+	Size       int
+	MinBits    int
+	MaxBits    int
+	DirectBits int
+}
+
+// The kinds of the containers of a section.
+var (
+	BlockStates = Kind{Size: SectionVolume, MinBits: 4, MaxBits: 8, DirectBits: 15}
+	Biomes      = Kind{Size: BiomeVolume, MinBits: 1, MaxBits: 3, DirectBits: 7}
+)
+
+// DirectBits will return the bits of a direct palette over a registry of n entries.
+func DirectBits(n int) int {
+	return BitsFor(n)
+}
+
+// BlockStateBits will return the bits of the direct palette of block states
+// of the protocol version, which follow the number of block states of the
+// version.
+func BlockStateBits(protocol uint16) int {
+	switch {
+	case protocol >= version.V1_16:
+		return 15
+	case protocol >= version.V1_13:
+		return 14
+	}
+
+	return 13
+}
+
+// Container is a paletted container, the block states or biomes of a
+// section. It holds a single value without storage, an indirect palette
+// with indexes into it, or global IDs.
+type Container struct {
+	kind    Kind
+	palette []int32
+	storage *BitStorage
+}
+
+// NewContainer will create a container holding only value.
+func NewContainer(kind Kind, value int32) *Container {
+	return &Container{kind: kind, palette: []int32{value}}
+}
+
+// Kind will return the kind of the container.
+func (c *Container) Kind() Kind {
+	return c.kind
+}
+
+// Bits will return the bits per entry, 0 for a single value.
+func (c *Container) Bits() int {
+	if c.storage == nil {
+		return 0
+	}
+
+	return c.storage.Bits()
+}
+
+// Palette will return the palette, nil for a direct container.
+func (c *Container) Palette() []int32 {
+	return c.palette
+}
+
+// Get will return value i, in YZX order.
+func (c *Container) Get(i int) int32 {
+	switch {
+	case c.storage == nil:
+		return c.palette[0]
+	case c.palette == nil:
+		return int32(c.storage.Get(i))
+	}
+
+	return c.palette[c.storage.Get(i)]
+}
+
+// Set will set value i, in YZX order, growing the palette as needed.
+func (c *Container) Set(i int, v int32) {
+	if c.palette != nil {
+		idx := c.index(v)
+		switch {
+		case idx < 0:
+			idx = len(c.palette)
+			c.palette = append(c.palette, v)
+			if BitsFor(len(c.palette)) > c.Bits() {
+				c.resize()
+			}
+		case c.storage == nil:
+			// v is already the single value.
+			return
+		}
+
+		if c.palette != nil {
+			c.storage.Set(i, idx)
+			return
+		}
+	}
+
+	if bits := BitsFor(int(v) + 1); bits > c.storage.Bits() {
+		c.widen(bits)
+	}
+	c.storage.Set(i, int(v))
+}
+
+// widen will move the global IDs of a direct container into storage of the bits.
+func (c *Container) widen(bits int) {
+	storage := NewBitStorage(bits, c.kind.Size, false)
+	for i := 0; i < c.kind.Size; i++ {
+		storage.Set(i, c.storage.Get(i))
+	}
+	c.storage = storage
+}
+
+// Fill will set every value to v, which turns the container into a single value.
+func (c *Container) Fill(v int32) {
+	c.palette = []int32{v}
+	c.storage = nil
+}
+
+// index will return the palette index of v, or -1.
+func (c *Container) index(v int32) int {
+	for i, p := range c.palette {
+		if p == v {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// resize will move the values into storage fitting the palette, which
+// already holds the new value.
+func (c *Container) resize() {
+	bits := BitsFor(len(c.palette))
+	if bits < c.kind.MinBits {
+		bits = c.kind.MinBits
+	}
+
+	old := c.storage
+	get := func(i int) int { return 0 }
+	if old != nil {
+		get = old.Get
+	}
+
+	if bits > c.kind.MaxBits {
+		direct := c.kind.DirectBits
+		for _, v := range c.palette {
+			if b := BitsFor(int(v) + 1); b > direct {
+				direct = b
+			}
+		}
+
+		storage := NewBitStorage(direct, c.kind.Size, false)
+		for i := 0; i < c.kind.Size; i++ {
+			storage.Set(i, int(c.palette[get(i)]))
+		}
+		c.palette, c.storage = nil, storage
+		return
+	}
+
+	storage := NewBitStorage(bits, c.kind.Size, false)
+	if old != nil {
+		for i := 0; i < c.kind.Size; i++ {
+			storage.Set(i, get(i))
+		}
+	}
+	c.storage = storage
+}
@@ -0,0 +1,575 @@
+package chunk
+
+import (
+	"encoding/binary"
+	"io"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/packet"
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// Dimension is what decoding a column needs to know of its dimension, which
+// the client learns from the Join Game and Respawn packets.
+type Dimension struct {
+	MinY   int
+	Height int
+	// SkyLight tells whether sections carry sky light, which is sent with
+	// them before 1.14.
+	SkyLight bool
+}
+
+// ReadColumn will decode the column of a Chunk Data packet of the protocol
+// version, read after its packet ID. Before 1.18 sections the packet does
+// not hold are left empty, and block entities keep their position and id in
+// their data, with a Type of -1.
+func ReadColumn(r io.Reader, protocol uint16, dim Dimension) (*Column, error) {
+	switch {
+	case protocol < version.V1_8:
+		return nil, ErrUnsupportedVersion
+	case protocol >= version.V1_18:
+		p, err := readChunkPacket(r, protocol)
+		if err != nil {
+			return nil, err
+		}
+		return FromPacket(p, protocol, dim)
+	}
+
+	x, err := util.ReadInt32(r)
+	if err != nil {
+		return nil, err
+	}
+	z, err := util.ReadInt32(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewColumn(x, z, dim.MinY, dim.Height)
+	if protocol < version.V1_9 {
+		return c, c.readLegacy(r, dim)
+	}
+
+	return c, c.readPaletted(r, protocol, dim)
+}
+
+// FromPacket will decode the column of a Chunk Data and Update Light packet
+// of 1.18 or later.
+func FromPacket(p packet.PlayChunkData, protocol uint16, dim Dimension) (*Column, error) {
+	if protocol < version.V1_18 {
+		return nil, ErrUnsupportedVersion
+	}
+
+	c := NewColumn(int32(p.ChunkX), int32(p.ChunkZ), dim.MinY, dim.Height)
+	for name, data := range p.Heightmaps {
+		c.Heightmaps[name] = data
+	}
+
+	b := util.NewBuffer(p.Data)
+	for i := range c.Sections {
+		s, err := readSection(b, protocol)
+		if err != nil {
+			return nil, err
+		}
+		c.Sections[i] = s
+	}
+
+	for _, e := range p.BlockEntities {
+		data, _ := e.Data.V.(nbt.Compound)
+		c.BlockEntities = append(c.BlockEntities, BlockEntity{
+			X:    int(e.PackedXZ) >> 4,
+			Y:    int(e.Y),
+			Z:    int(e.PackedXZ) & 15,
+			Type: int32(e.Type),
+			Data: data,
+		})
+	}
+
+	return c, c.ApplyLight(p.Light)
+}
+
+// ApplyLight will set the light of the column to the light sent in a Chunk
+// Data and Update Light or an Update Light packet, of 1.17 or later.
+func (c *Column) ApplyLight(light packet.LightData) error {
+	if err := applyLight(c.SkyLight, light.SkyLightMask, light.EmptySkyLightMask, light.SkyLight); err != nil {
+		return err
+	}
+
+	return applyLight(c.BlockLight, light.BlockLightMask, light.EmptyBlockLightMask, light.BlockLight)
+}
+
+func applyLight(light []NibbleArray, mask, empty codecs.BitSet, arrays []codecs.ByteArray) error {
+	if mask.Len() > len(light) || empty.Len() > len(light) {
+		return ErrOutOfBounds
+	}
+
+	for i := range light {
+		switch {
+		case mask.Get(i):
+			if len(arrays) == 0 || len(arrays[0]) != SectionVolume/2 {
+				return ErrInvalidStorage
+			}
+			light[i] = append(NibbleArray(nil), arrays[0]...)
+			arrays = arrays[1:]
+		case empty.Get(i):
+			light[i] = NewNibbleArray()
+		}
+	}
+
+	return nil
+}
+
+// readChunkPacket will read a Chunk Data and Update Light packet.
+func readChunkPacket(r io.Reader, protocol uint16) (p packet.PlayChunkData, err error) {
+	x, err := util.ReadInt32(r)
+	if err != nil {
+		return
+	}
+	z, err := util.ReadInt32(r)
+	if err != nil {
+		return
+	}
+	p.ChunkX, p.ChunkZ = codecs.Int(x), codecs.Int(z)
+
+	maps, err := codecs.Heightmaps{}.DecodeVersion(r, protocol)
+	if err != nil {
+		return
+	}
+	p.Heightmaps = maps.(codecs.Heightmaps)
+
+	data, err := codecs.ByteArray{}.Decode(r)
+	if err != nil {
+		return
+	}
+	p.Data = data.([]byte)
+
+	count, err := readCount(r)
+	if err != nil {
+		return
+	}
+	for i := 0; i < count; i++ {
+		var e packet.ChunkBlockEntity
+		xz, err := util.ReadUint8(r)
+		if err != nil {
+			return p, err
+		}
+		y, err := util.ReadInt16(r)
+		if err != nil {
+			return p, err
+		}
+		typ, err := util.ReadVarInt(r)
+		if err != nil {
+			return p, err
+		}
+		tag, err := codecs.NBT{}.DecodeVersion(r, protocol)
+		if err != nil {
+			return p, err
+		}
+
+		e.PackedXZ, e.Y, e.Type, e.Data = codecs.UnsignedByte(xz), codecs.Short(y), codecs.VarInt(typ), tag.(codecs.NBT)
+		p.BlockEntities = append(p.BlockEntities, e)
+	}
+
+	p.Light, err = readLight(r, protocol)
+	return
+}
+
+// readLight will read the light data of a Chunk Data and Update Light or an
+// Update Light packet.
+func readLight(r io.Reader, protocol uint16) (light packet.LightData, err error) {
+	if protocol < version.V1_20 {
+		var trust bool
+		if trust, err = util.ReadBool(r); err != nil {
+			return
+		}
+		light.TrustEdges = codecs.Boolean(trust)
+	}
+
+	for _, mask := range []*codecs.BitSet{&light.SkyLightMask, &light.BlockLightMask, &light.EmptySkyLightMask, &light.EmptyBlockLightMask} {
+		var v interface{}
+		if v, err = (codecs.BitSet{}).Decode(r); err != nil {
+			return
+		}
+		*mask = v.(codecs.BitSet)
+	}
+
+	for _, arrays := range []*[]codecs.ByteArray{&light.SkyLight, &light.BlockLight} {
+		var count int
+		if count, err = readCount(r); err != nil {
+			return
+		}
+		for i := 0; i < count; i++ {
+			var v interface{}
+			if v, err = (codecs.ByteArray{}).DecodeMax(r, SectionVolume/2); err != nil {
+				return
+			}
+			*arrays = append(*arrays, v.([]byte))
+		}
+	}
+
+	return
+}
+
+// readCount will read a VarInt count of a list, rejecting negative counts.
+func readCount(r io.Reader) (int, error) {
+	count, err := util.ReadVarInt(r)
+	if err == nil && count < 0 {
+		err = codecs.ErrInvalidLength
+	}
+
+	return count, err
+}
+
+// readSection will read a section in the layout of 1.18 and later.
+func readSection(b *util.Buffer, protocol uint16) (*Section, error) {
+	if _, err := b.GetInt16(); err != nil {
+		return nil, err
+	}
+
+	states, err := readContainer(b, BlockStates, protocol)
+	if err != nil {
+		return nil, err
+	}
+	biomes, err := readContainer(b, Biomes, protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Section{States: states, Biomes: biomes}, nil
+}
+
+// readContainer will read a paletted container. Like the vanilla client it
+// widens indirect palettes to the smallest bits of the kind, and a direct
+// container keeps the bits it was sent with.
+func readContainer(b *util.Buffer, kind Kind, protocol uint16) (*Container, error) {
+	wireBits, err := b.GetUint8()
+	if err != nil {
+		return nil, err
+	}
+	bits := int(wireBits)
+	if bits > 32 {
+		return nil, ErrInvalidStorage
+	}
+
+	c := &Container{kind: kind}
+	switch {
+	case bits == 0 && protocol >= version.V1_18:
+		v, err := b.GetVarInt()
+		if err != nil {
+			return nil, err
+		}
+		c.palette = []int32{int32(v)}
+	case bits <= kind.MaxBits:
+		if bits < kind.MinBits {
+			bits = kind.MinBits
+		}
+
+		l, err := b.GetVarInt()
+		if err != nil {
+			return nil, err
+		}
+		if l < 1 || l > 1<<uint(bits) {
+			return nil, ErrInvalidStorage
+		}
+
+		c.palette = make([]int32, l)
+		for i := range c.palette {
+			v, err := b.GetVarInt()
+			if err != nil {
+				return nil, err
+			}
+			c.palette[i] = int32(v)
+		}
+	case protocol < version.V1_13:
+		// The direct palette has a length of 0 before 1.13.
+		if _, err := b.GetVarInt(); err != nil {
+			return nil, err
+		}
+	}
+
+	span := protocol < version.V1_16
+	length := StorageLength(bits, kind.Size, span)
+	if protocol < version.V1_21_5 {
+		l, err := b.GetVarInt()
+		if err != nil {
+			return nil, err
+		}
+		// Single values are sent with an empty array.
+		if l != length {
+			return nil, ErrInvalidStorage
+		}
+	}
+
+	if length == 0 {
+		return c, nil
+	}
+
+	data := make([]uint64, length)
+	for i := range data {
+		if data[i], err = b.GetUint64(); err != nil {
+			return nil, err
+		}
+	}
+	if c.storage, err = NewBitStorageData(bits, kind.Size, span, data); err != nil {
+		return nil, err
+	}
+	if c.palette != nil {
+		for i := 0; i < kind.Size; i++ {
+			if c.storage.Get(i) >= len(c.palette) {
+				return nil, ErrInvalidStorage
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// readPaletted will read the rest of a Chunk Data packet of 1.9 to 1.17.
+func (c *Column) readPaletted(r io.Reader, protocol uint16, dim Dimension) error {
+	full := true
+	if protocol < version.V1_17 {
+		v, err := util.ReadBool(r)
+		if err != nil {
+			return err
+		}
+		full = v
+	}
+	if protocol >= version.V1_16 && protocol < version.V1_16_2 {
+		// Ignore old data.
+		if _, err := util.ReadBool(r); err != nil {
+			return err
+		}
+	}
+
+	var mask codecs.BitSet
+	if protocol >= version.V1_17 {
+		v, err := (codecs.BitSet{}).Decode(r)
+		if err != nil {
+			return err
+		}
+		mask = v.(codecs.BitSet)
+	} else {
+		v, err := util.ReadVarInt(r)
+		if err != nil {
+			return err
+		}
+		mask = codecs.BitSet{int64(uint32(v))}
+	}
+	if mask.Len() > len(c.Sections) {
+		return ErrOutOfBounds
+	}
+
+	if protocol >= version.V1_14 {
+		maps, err := codecs.Heightmaps{}.DecodeVersion(r, protocol)
+		if err != nil {
+			return err
+		}
+		for name, data := range maps.(codecs.Heightmaps) {
+			c.Heightmaps[name] = data
+		}
+	}
+
+	if full && protocol >= version.V1_15 {
+		if err := c.readBiomes(r, protocol); err != nil {
+			return err
+		}
+	}
+
+	data, err := codecs.ByteArray{}.Decode(r)
+	if err != nil {
+		return err
+	}
+	b := util.NewBuffer(data.([]byte))
+
+	for i := range c.Sections {
+		if !mask.Get(i) {
+			continue
+		}
+
+		if protocol >= version.V1_14 {
+			if _, err := b.GetInt16(); err != nil {
+				return err
+			}
+		}
+
+		states, err := readContainer(b, BlockStates, protocol)
+		if err != nil {
+			return err
+		}
+		c.Sections[i].States = states
+
+		if protocol < version.V1_14 {
+			if c.BlockLight[i+1], err = readNibbles(b); err != nil {
+				return err
+			}
+			if dim.SkyLight {
+				if c.SkyLight[i+1], err = readNibbles(b); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if full && protocol < version.V1_15 {
+		var biomes [256]int32
+		for i := range biomes {
+			if protocol < version.V1_13 {
+				v, err := b.GetUint8()
+				biomes[i] = int32(v)
+				if err != nil {
+					return err
+				}
+			} else if biomes[i], err = b.GetInt32(); err != nil {
+				return err
+			}
+		}
+		c.setFlatBiomes(&biomes)
+	}
+
+	if protocol < version.V1_9_4 {
+		return nil
+	}
+
+	count, err := readCount(r)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		tag, err := codecs.NBT{}.DecodeVersion(r, protocol)
+		if err != nil {
+			return err
+		}
+		data, _ := tag.(codecs.NBT).V.(nbt.Compound)
+		x, _ := data.Int("x")
+		y, _ := data.Int("y")
+		z, _ := data.Int("z")
+
+		c.BlockEntities = append(c.BlockEntities, BlockEntity{X: int(x) & 15, Y: int(y), Z: int(z) & 15, Type: -1, Data: data})
+	}
+
+	return nil
+}
+
+// readBiomes will read the 4x4x4 biome cells of the column of 1.15 to 1.17,
+// in YZX order from the bottom of the column.
+func (c *Column) readBiomes(r io.Reader, protocol uint16) error {
+	count := 1024
+	if protocol >= version.V1_16_2 {
+		var err error
+		if count, err = readCount(r); err != nil {
+			return err
+		}
+	}
+	if count > len(c.Sections)*BiomeVolume {
+		return ErrOutOfBounds
+	}
+
+	for i := 0; i < count; i++ {
+		var (
+			v   int
+			err error
+		)
+		if protocol >= version.V1_16_2 {
+			v, err = util.ReadVarInt(r)
+		} else {
+			var i32 int32
+			i32, err = util.ReadInt32(r)
+			v = int(i32)
+		}
+		if err != nil {
+			return err
+		}
+
+		c.Sections[i/BiomeVolume].Biomes.Set(i%BiomeVolume, int32(v))
+	}
+
+	return nil
+}
+
+// setFlatBiomes will set the biomes of the column from the biome of every
+// block column, in ZX order, as sent before 1.15. Each cell takes the biome
+// of its corner.
+func (c *Column) setFlatBiomes(biomes *[256]int32) {
+	for _, s := range c.Sections {
+		for i := 0; i < BiomeVolume; i++ {
+			x, z := i&3*4, i>>2&3*4
+			s.Biomes.Set(i, biomes[z<<4|x])
+		}
+	}
+}
+
+// readLegacy will read the rest of a Chunk Data packet of 1.8, whose blocks
+// are little endian block IDs and metadata, of every sent section, followed
+// by their block light, sky light and the biomes.
+func (c *Column) readLegacy(r io.Reader, dim Dimension) error {
+	full, err := util.ReadBool(r)
+	if err != nil {
+		return err
+	}
+	mask, err := util.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	if BitsFor(int(mask)+1) > len(c.Sections) {
+		return ErrOutOfBounds
+	}
+
+	data, err := codecs.ByteArray{}.Decode(r)
+	if err != nil {
+		return err
+	}
+	b := util.NewBuffer(data.([]byte))
+
+	var sections []int
+	for i := range c.Sections {
+		if mask&(1<<uint(i)) != 0 {
+			sections = append(sections, i)
+		}
+	}
+
+	for _, i := range sections {
+		p, err := b.GetBytes(SectionVolume * 2)
+		if err != nil {
+			return err
+		}
+		for j := 0; j < SectionVolume; j++ {
+			c.Sections[i].States.Set(j, int32(binary.LittleEndian.Uint16(p[j*2:])))
+		}
+	}
+	for _, i := range sections {
+		if c.BlockLight[i+1], err = readNibbles(b); err != nil {
+			return err
+		}
+	}
+	if dim.SkyLight {
+		for _, i := range sections {
+			if c.SkyLight[i+1], err = readNibbles(b); err != nil {
+				return err
+			}
+		}
+	}
+
+	if full {
+		p, err := b.GetBytes(256)
+		if err != nil {
+			return err
+		}
+		var biomes [256]int32
+		for i, v := range p {
+			biomes[i] = int32(v)
+		}
+		c.setFlatBiomes(&biomes)
+	}
+
+	return nil
+}
+
+func readNibbles(b *util.Buffer) (NibbleArray, error) {
+	p, err := b.GetBytes(SectionVolume / 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(NibbleArray(nil), p...), nil
+}
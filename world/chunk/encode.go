@@ -0,0 +1,137 @@
+package chunk
+
+import (
+	"justanother.org/protocolhelper/protocol/codecs"
+	"justanother.org/protocolhelper/protocol/packet"
+	"justanother.org/protocolhelper/protocol/version"
+	"justanother.org/protocolhelper/util"
+)
+
+// ChunkData will return the Chunk Data and Update Light packet of the column
+// for the protocol version, which must be 1.18 or later. Biomes is the number
+// of entries of the biome registry sent to the client, which sets the bits
+// of biome IDs sent directly.
+func (c *Column) ChunkData(protocol uint16, biomes int) (packet.PlayChunkData, error) {
+	if protocol < version.V1_18 {
+		return packet.PlayChunkData{}, ErrUnsupportedVersion
+	}
+
+	b := util.AcquireBuffer()
+	defer util.ReleaseBuffer(b)
+	stateBits, biomeBits := BlockStateBits(protocol), DirectBits(biomes)
+	for _, s := range c.Sections {
+		b.PutInt16(int16(s.BlockCount()))
+		s.States.encode(b, protocol, stateBits)
+		s.Biomes.encode(b, protocol, biomeBits)
+	}
+
+	p := packet.PlayChunkData{
+		ChunkX:     codecs.Int(c.X),
+		ChunkZ:     codecs.Int(c.Z),
+		Heightmaps: codecs.Heightmaps(c.Heightmaps),
+		Data:       append(codecs.ByteArray(nil), b.Bytes()...),
+		Light:      c.lightData(),
+	}
+	for _, e := range c.BlockEntities {
+		p.BlockEntities = append(p.BlockEntities, packet.ChunkBlockEntity{
+			PackedXZ: codecs.UnsignedByte((e.X&15)<<4 | e.Z&15),
+			Y:        codecs.Short(e.Y),
+			Type:     codecs.VarInt(e.Type),
+			Data:     codecs.NBT{V: e.Data},
+		})
+	}
+
+	return p, nil
+}
+
+// UpdateLight will return the Update Light packet of the column for the
+// protocol version, which must be 1.18 or later.
+func (c *Column) UpdateLight(protocol uint16) (packet.PlayUpdateLight, error) {
+	if protocol < version.V1_18 {
+		return packet.PlayUpdateLight{}, ErrUnsupportedVersion
+	}
+
+	return packet.PlayUpdateLight{
+		ChunkX: codecs.VarInt(c.X),
+		ChunkZ: codecs.VarInt(c.Z),
+		Light:  c.lightData(),
+	}, nil
+}
+
+// lightData will return the light of the column. Dark sections are sent in
+// the empty masks without their array.
+func (c *Column) lightData() packet.LightData {
+	light := packet.LightData{TrustEdges: true}
+	light.SkyLightMask, light.EmptySkyLightMask, light.SkyLight = lightMasks(c.SkyLight)
+	light.BlockLightMask, light.EmptyBlockLightMask, light.BlockLight = lightMasks(c.BlockLight)
+
+	return light
+}
+
+func lightMasks(light []NibbleArray) (mask, empty codecs.BitSet, arrays []codecs.ByteArray) {
+	mask, empty, arrays = codecs.BitSet{}, codecs.BitSet{}, []codecs.ByteArray{}
+	for i, n := range light {
+		switch {
+		case n == nil:
+		case n.IsEmpty():
+			empty.Set(i, true)
+		default:
+			mask.Set(i, true)
+			arrays = append(arrays, codecs.ByteArray(n))
+		}
+	}
+
+	return
+}
+
+// encode will write the container in the layout of 1.18 and later, with
+// global IDs of the direct bits. The length of the long array is left out
+// since 1.21.5, the client knows it from the bits.
+func (c *Container) encode(b *util.Buffer, protocol uint16, direct int) {
+	storage := c.storage
+	if storage != nil {
+		storage = c.wireStorage(direct)
+	}
+
+	if storage == nil {
+		b.PutUint8(0)
+	} else {
+		b.PutUint8(uint8(storage.Bits()))
+	}
+	switch {
+	case c.storage == nil:
+		b.PutVarInt(int(c.palette[0]))
+	case c.palette != nil:
+		b.PutVarInt(len(c.palette))
+		for _, v := range c.palette {
+			b.PutVarInt(int(v))
+		}
+	}
+
+	var data []uint64
+	if storage != nil {
+		data = storage.Data()
+	}
+	if protocol < version.V1_21_5 {
+		b.PutVarInt(len(data))
+	}
+	for _, v := range data {
+		b.PutUint64(v)
+	}
+}
+
+// wireStorage will return the storage as sent since 1.16, without values
+// spanning two longs and with global IDs of the direct bits. Containers read
+// from older versions are repacked.
+func (c *Container) wireStorage(direct int) *BitStorage {
+	s := c.storage
+	if c.palette != nil || s.Bits() == direct {
+		return s.Repack(false)
+	}
+
+	n := NewBitStorage(direct, s.Len(), false)
+	for i := 0; i < s.Len(); i++ {
+		n.Set(i, s.Get(i))
+	}
+	return n
+}
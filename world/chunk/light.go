@@ -0,0 +1,42 @@
+package chunk
+
+// NibbleArray is the light of a section, a level from 0 to 15 for every
+// block packed two to a byte, the even index in the low nibble.
+type NibbleArray []byte
+
+// NewNibbleArray will create an array of a dark section.
+func NewNibbleArray() NibbleArray {
+	return make(NibbleArray, SectionVolume/2)
+}
+
+// Get will return the light level at the block of the section.
+func (n NibbleArray) Get(x, y, z int) int {
+	i := Index(x, y, z)
+	return int(n[i/2]>>(uint(i&1)*4)) & 15
+}
+
+// Set will set the light level at the block of the section.
+func (n NibbleArray) Set(x, y, z, level int) {
+	i := Index(x, y, z)
+	shift := uint(i&1) * 4
+	n[i/2] = n[i/2]&^(15<<shift) | byte(level&15)<<shift
+}
+
+// Fill will set every light level to level.
+func (n NibbleArray) Fill(level int) {
+	b := byte(level&15) * 0x11
+	for i := range n {
+		n[i] = b
+	}
+}
+
+// IsEmpty will report whether every light level is 0.
+func (n NibbleArray) IsEmpty() bool {
+	for _, b := range n {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package chunk
+
+import "math/bits"
+
+// BitStorage is an array of unsigned values of a fixed number of bits,
+// packed into longs from the least significant bit. Since 1.16 a value never
+// spans two longs and the remaining bits of each long are padding; before,
+// values are packed back to back and may span two longs.
+type BitStorage struct {
+	bits int
+	size int
+	span bool
+	mask uint64
+	data []uint64
+}
+
+// NewBitStorage will create a zeroed storage of size values of the bits.
+func NewBitStorage(bits, size int, span bool) *BitStorage {
+	return &BitStorage{
+		bits: bits,
+		size: size,
+		span: span,
+		mask: 1<<uint(bits) - 1,
+		data: make([]uint64, StorageLength(bits, size, span)),
+	}
+}
+
+// NewBitStorageData will create a storage over packed data, which must be
+// StorageLength longs long.
+func NewBitStorageData(bits, size int, span bool, data []uint64) (*BitStorage, error) {
+	if bits < 1 || bits > 32 || len(data) != StorageLength(bits, size, span) {
+		return nil, ErrInvalidStorage
+	}
+
+	return &BitStorage{bits: bits, size: size, span: span, mask: 1<<uint(bits) - 1, data: data}, nil
+}
+
+// StorageLength will return the number of longs holding size values of the bits.
+func StorageLength(bits, size int, span bool) int {
+	if bits == 0 {
+		return 0
+	}
+	if span {
+		return (size*bits + 63) / 64
+	}
+
+	perLong := 64 / bits
+	return (size + perLong - 1) / perLong
+}
+
+// Bits will return the number of bits of a value.
+func (s *BitStorage) Bits() int {
+	return s.bits
+}
+
+// Len will return the number of values.
+func (s *BitStorage) Len() int {
+	return s.size
+}
+
+// Data will return the packed longs. They share the memory of the storage.
+func (s *BitStorage) Data() []uint64 {
+	return s.data
+}
+
+// Get will return value i.
+func (s *BitStorage) Get(i int) int {
+	if !s.span {
+		perLong := 64 / s.bits
+		off := uint(i % perLong * s.bits)
+		return int(s.data[i/perLong] >> off & s.mask)
+	}
+
+	bit := i * s.bits
+	long, off := bit/64, uint(bit%64)
+	v := s.data[long] >> off
+	if int(off)+s.bits > 64 {
+		v |= s.data[long+1] << (64 - off)
+	}
+	return int(v & s.mask)
+}
+
+// Set will set value i. Bits of v above the width of the storage are dropped.
+func (s *BitStorage) Set(i, v int) {
+	val := uint64(v) & s.mask
+	if !s.span {
+		perLong := 64 / s.bits
+		off := uint(i % perLong * s.bits)
+		long := &s.data[i/perLong]
+		*long = *long&^(s.mask<<off) | val<<off
+		return
+	}
+
+	bit := i * s.bits
+	long, off := bit/64, uint(bit%64)
+	s.data[long] = s.data[long]&^(s.mask<<off) | val<<off
+	if int(off)+s.bits > 64 {
+		high := 64 - off
+		s.data[long+1] = s.data[long+1]&^(s.mask>>high) | val>>high
+	}
+}
+
+// Repack will return the storage with the packing of span.
+func (s *BitStorage) Repack(span bool) *BitStorage {
+	if s.span == span {
+		return s
+	}
+
+	n := NewBitStorage(s.bits, s.size, span)
+	for i := 0; i < s.size; i++ {
+		n.Set(i, s.Get(i))
+	}
+	return n
+}
+
+// BitsFor will return the number of bits needed to tell n values apart.
+func BitsFor(n int) int {
+	if n <= 1 {
+		return 0
+	}
+
+	return bits.Len(uint(n - 1))
+}
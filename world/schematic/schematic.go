@@ -0,0 +1,196 @@
+// Package schematic reads the files builds are exported to, Sponge
+// schematics (.schem, versions 1 to 3) and vanilla structures (.nbt), into a
+// block volume which can be pasted into chunk columns.
+package schematic
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/world/blocks"
+	"justanother.org/protocolhelper/world/chunk"
+)
+
+// Possible Errors.
+var (
+	// ErrUnknownFormat is returned for files which are neither a Sponge schematic nor a structure.
+	ErrUnknownFormat = errors.New("schematic: unknown format")
+	// ErrInvalid is returned for schematics whose contents do not fit their size.
+	ErrInvalid = errors.New("schematic: invalid schematic")
+	// ErrUnknownName is returned when pasting block states or block entities which have no ID.
+	ErrUnknownName = errors.New("schematic: unknown name")
+)
+
+// StructureVoid is the block structures use for positions which keep the
+// block they are pasted over.
+const StructureVoid = "minecraft:structure_void"
+
+// BlockEntity is a block entity of a volume, at a position relative to it.
+type BlockEntity struct {
+	X, Y, Z int
+	ID      string
+	// Data holds the NBT of the block entity without its id and position.
+	Data nbt.Compound
+}
+
+// Volume is a box of blocks, as block states written like in commands.
+type Volume struct {
+	// Width, Height and Length are the size of the volume along X, Y and Z.
+	Width, Height, Length int
+	// Offset is the position of the volume relative to the point it was
+	// copied from, for Sponge schematics.
+	Offset      [3]int
+	DataVersion int32
+
+	// Palette holds the block states of the volume and Blocks the index of
+	// the state of every block in YZX order.
+	Palette       []string
+	Blocks        []int
+	BlockEntities []BlockEntity
+}
+
+// index will return the index of the block in Blocks.
+func (v *Volume) index(x, y, z int) int {
+	return (y*v.Length+z)*v.Width + x
+}
+
+// At will return the block state at the position relative to the volume.
+func (v *Volume) At(x, y, z int) string {
+	if x < 0 || y < 0 || z < 0 || x >= v.Width || y >= v.Height || z >= v.Length {
+		return ""
+	}
+
+	return v.Palette[v.Blocks[v.index(x, y, z)]]
+}
+
+// IDs maps the names of a volume to the IDs of a version. A blocks.Registry
+// provides the block states.
+type IDs interface {
+	BlockState(name string, properties map[string]string) (int32, bool)
+	BlockEntity(name string) (int32, bool)
+}
+
+// Paste will paste the part of the volume inside the column, with the
+// lowest corner of the volume at the world position. Structure voids keep
+// the blocks they are pasted over.
+func (v *Volume) Paste(c *chunk.Column, x, y, z int, ids IDs) error {
+	states := make([]int32, len(v.Palette))
+	for i, s := range v.Palette {
+		if s == StructureVoid {
+			states[i] = -1
+			continue
+		}
+
+		name, props, err := blocks.ParseState(s)
+		if err != nil {
+			return err
+		}
+		id, ok := ids.BlockState(name, props)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownName, s)
+		}
+		states[i] = id
+	}
+
+	// The box of the volume within the column, relative to the volume.
+	minX, maxX := clamp(int(c.X)*16-x, v.Width), clamp(int(c.X)*16+16-x, v.Width)
+	minZ, maxZ := clamp(int(c.Z)*16-z, v.Length), clamp(int(c.Z)*16+16-z, v.Length)
+	minY, maxY := clamp(c.MinY-y, v.Height), clamp(c.MinY+c.Height()-y, v.Height)
+
+	for vy := minY; vy < maxY; vy++ {
+		for vz := minZ; vz < maxZ; vz++ {
+			for vx := minX; vx < maxX; vx++ {
+				state := states[v.Blocks[v.index(vx, vy, vz)]]
+				if state < 0 {
+					continue
+				}
+				c.SetBlock((x+vx)&15, y+vy, (z+vz)&15, state)
+			}
+		}
+	}
+
+	// Block entities of the column in the box are replaced, unless a
+	// structure void is pasted over them.
+	kept := c.BlockEntities[:0]
+	for _, e := range c.BlockEntities {
+		vx, vy, vz := int(c.X)*16+e.X-x, e.Y-y, int(c.Z)*16+e.Z-z
+		if vx < minX || vx >= maxX || vy < minY || vy >= maxY || vz < minZ || vz >= maxZ || states[v.Blocks[v.index(vx, vy, vz)]] < 0 {
+			kept = append(kept, e)
+		}
+	}
+	c.BlockEntities = kept
+
+	for _, e := range v.BlockEntities {
+		if e.X < minX || e.X >= maxX || e.Y < minY || e.Y >= maxY || e.Z < minZ || e.Z >= maxZ {
+			continue
+		}
+
+		typ, ok := ids.BlockEntity(e.ID)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownName, e.ID)
+		}
+		c.BlockEntities = append(c.BlockEntities, chunk.BlockEntity{X: (x + e.X) & 15, Y: y + e.Y, Z: (z + e.Z) & 15, Type: typ, Data: e.Data})
+	}
+
+	return nil
+}
+
+// clamp will clamp v to [0, max].
+func clamp(v, max int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return max
+	}
+
+	return v
+}
+
+// Load will read the schematic or structure file at the path.
+func Load(path string) (*Volume, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Read will read a schematic or structure, gzip compressed or not.
+func Read(r io.Reader) (*Volume, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		r = gz
+	} else {
+		r = br
+	}
+
+	c, err := nbt.ReadCompound(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := c.Compound("Schematic"); ok {
+		return FromSponge(s)
+	}
+	if _, ok := c["Version"]; ok {
+		return FromSponge(c)
+	}
+	if _, ok := c["size"]; ok {
+		return FromStructure(c)
+	}
+
+	return nil, ErrUnknownFormat
+}
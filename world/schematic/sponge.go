@@ -0,0 +1,109 @@
+package schematic
+
+import (
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/util"
+	"justanother.org/protocolhelper/world/blocks"
+)
+
+// FromSponge will read a Sponge schematic of version 1, 2 or 3 from its
+// Schematic compound.
+func FromSponge(c nbt.Compound) (*Volume, error) {
+	version, _ := c.Number("Version")
+
+	// Blocks are in the Blocks compound since version 3.
+	b, data, entities := c, "BlockData", "BlockEntities"
+	switch version {
+	case 1:
+		entities = "TileEntities"
+	case 2:
+	case 3:
+		var ok bool
+		if b, ok = c.Compound("Blocks"); !ok {
+			return nil, ErrInvalid
+		}
+		data = "Data"
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	// The sizes are unsigned shorts.
+	width, _ := c.Short("Width")
+	height, _ := c.Short("Height")
+	length, _ := c.Short("Length")
+	dataVersion, _ := c.Number("DataVersion")
+	v := &Volume{
+		Width:       int(uint16(width)),
+		Height:      int(uint16(height)),
+		Length:      int(uint16(length)),
+		DataVersion: int32(dataVersion),
+	}
+	if offset, ok := c.IntArray("Offset"); ok && len(offset) == 3 {
+		v.Offset = [3]int{int(offset[0]), int(offset[1]), int(offset[2])}
+	}
+
+	palette, ok := b.Compound("Palette")
+	if !ok {
+		return nil, ErrInvalid
+	}
+	v.Palette = make([]string, len(palette))
+	for s := range palette {
+		i, ok := palette.Number(s)
+		if !ok || i < 0 || int(i) >= len(palette) || v.Palette[i] != "" {
+			return nil, ErrInvalid
+		}
+
+		name, props, err := blocks.ParseState(s)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+		v.Palette[i] = blocks.FormatState(name, props)
+	}
+
+	indexes, _ := b.ByteArray(data)
+	volume := v.Width * v.Height * v.Length
+	// Every index takes at least a byte.
+	if len(indexes) < volume {
+		return nil, ErrInvalid
+	}
+
+	buf := util.NewBuffer(indexes)
+	v.Blocks = make([]int, volume)
+	for i := range v.Blocks {
+		idx, err := buf.GetVarInt()
+		if err != nil || idx < 0 || idx >= len(v.Palette) {
+			return nil, ErrInvalid
+		}
+		v.Blocks[i] = idx
+	}
+
+	list, _ := b.List(entities)
+	for _, item := range list {
+		e, ok := item.(nbt.Compound)
+		if !ok {
+			return nil, ErrInvalid
+		}
+		pos, ok := e.IntArray("Pos")
+		if !ok || len(pos) != 3 {
+			return nil, ErrInvalid
+		}
+		id, _ := e.String("Id")
+
+		be := BlockEntity{X: int(pos[0]), Y: int(pos[1]), Z: int(pos[2]), ID: id}
+		if version == 3 {
+			be.Data, _ = e.Compound("Data")
+		} else {
+			be.Data = make(nbt.Compound, len(e))
+			for k, val := range e {
+				switch k {
+				case "Pos", "Id", "ContentVersion":
+				default:
+					be.Data[k] = val
+				}
+			}
+		}
+		v.BlockEntities = append(v.BlockEntities, be)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,117 @@
+package schematic
+
+import (
+	"justanother.org/protocolhelper/nbt"
+	"justanother.org/protocolhelper/world/blocks"
+)
+
+// maxStructureVolume is the largest structure read. Structures only list
+// their blocks, so their size is not bound by the size of the file.
+const maxStructureVolume = 1 << 24
+
+// FromStructure will read a vanilla structure, as saved by structure blocks.
+// Positions the structure has no block for hold structure voids. Of
+// structures with several palettes the first is read.
+func FromStructure(c nbt.Compound) (*Volume, error) {
+	size, ok := listInts(c, "size")
+	if !ok {
+		return nil, ErrInvalid
+	}
+	// The size is checked one side at a time so its product cannot overflow.
+	volume := 1
+	for _, n := range size {
+		if n < 0 || n > maxStructureVolume || (n > 0 && volume > maxStructureVolume/n) {
+			return nil, ErrInvalid
+		}
+		volume *= n
+	}
+
+	dataVersion, _ := c.Number("DataVersion")
+	v := &Volume{Width: size[0], Height: size[1], Length: size[2], DataVersion: int32(dataVersion)}
+
+	palette, ok := c.List("palette")
+	if !ok {
+		palettes, _ := c.List("palettes")
+		if len(palettes) == 0 {
+			return nil, ErrInvalid
+		}
+		if palette, ok = palettes[0].(nbt.List); !ok {
+			return nil, ErrInvalid
+		}
+	}
+
+	for _, item := range palette {
+		state, ok := item.(nbt.Compound)
+		if !ok {
+			return nil, ErrInvalid
+		}
+		name, _ := state.String("Name")
+		name, _, err := blocks.ParseState(name)
+		if err != nil {
+			return nil, ErrInvalid
+		}
+
+		props := make(map[string]string)
+		p, _ := state.Compound("Properties")
+		for k := range p {
+			props[k], _ = p.String(k)
+		}
+		v.Palette = append(v.Palette, blocks.FormatState(name, props))
+	}
+
+	void := len(v.Palette)
+	v.Palette = append(v.Palette, StructureVoid)
+	v.Blocks = make([]int, volume)
+	for i := range v.Blocks {
+		v.Blocks[i] = void
+	}
+
+	list, _ := c.List("blocks")
+	for _, item := range list {
+		b, ok := item.(nbt.Compound)
+		if !ok {
+			return nil, ErrInvalid
+		}
+		state, ok := b.Number("state")
+		pos, okPos := listInts(b, "pos")
+		if !ok || !okPos || state < 0 || int(state) >= void ||
+			pos[0] < 0 || pos[1] < 0 || pos[2] < 0 || pos[0] >= v.Width || pos[1] >= v.Height || pos[2] >= v.Length {
+			return nil, ErrInvalid
+		}
+		v.Blocks[v.index(pos[0], pos[1], pos[2])] = int(state)
+
+		if data, ok := b.Compound("nbt"); ok {
+			id, _ := data.String("id")
+			be := BlockEntity{X: pos[0], Y: pos[1], Z: pos[2], ID: id, Data: make(nbt.Compound, len(data))}
+			for k, val := range data {
+				switch k {
+				case "id", "x", "y", "z":
+				default:
+					be.Data[k] = val
+				}
+			}
+			v.BlockEntities = append(v.BlockEntities, be)
+		}
+	}
+
+	return v, nil
+}
+
+// listInts will return the three ints of the list at the key.
+func listInts(c nbt.Compound, key string) ([3]int, bool) {
+	var v [3]int
+
+	list, ok := c.List(key)
+	if !ok || len(list) != 3 {
+		return v, false
+	}
+	for i, item := range list {
+		n, ok := item.(int32)
+		if !ok {
+			return v, false
+		}
+		v[i] = int(n)
+	}
+
+	return v, true
+}